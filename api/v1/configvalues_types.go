@@ -1,14 +1,61 @@
 package v1
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"maps"
+	"regexp"
 	"slices"
+	"strings"
 
 	"github.com/google/go-cmp/cmp"
 )
 
+// config values who's name ends in one of these are always treated as sensitive
+var sensitive_name_regex = regexp.MustCompile(`(?i)(password|passwd|secret|token|private_key)$`)
+
+// RedactedPrefix is prepended to the hash that replaces a sensitive value
+const RedactedPrefix = "hmac-sha256:"
+
+// redactionKey keys the hash of sensitive values so they can not be recovered by hashing guesses, it is set with
+// SetRedactionKey, the operator will not start without one so the redacted values are the same across restarts and
+// replicas
+var redactionKey []byte
+
+// SetRedactionKey sets the key used to hash sensitive values, it must be called before any values are redacted.
+func SetRedactionKey(key []byte) {
+	redactionKey = slices.Clone(key)
+}
+
+// IsSensitiveKey returns true if the config value name is sensitive, either by naming convention
+// or by being listed in sensitiveKeys.  The config name prefixes (<, >, -, ~) and the :qualifier suffix
+// are ignored when matching.
+func IsSensitiveKey(name string, sensitiveKeys []string) bool {
+	name = bareConfigName(name)
+	if sensitive_name_regex.MatchString(name) {
+		return true
+	}
+
+	for _, key := range sensitiveKeys {
+		if bareConfigName(key) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func bareConfigName(name string) string {
+	name = strings.TrimLeft(name, "<>-~")
+	if i := strings.Index(name, ":"); i != -1 {
+		name = name[:i]
+	}
+	return name
+}
+
 type ConfigValues map[string]ConfigValue
 
 func (cvs *ConfigValues) Value() map[string]any {
@@ -19,12 +66,29 @@ func (cvs *ConfigValues) Value() map[string]any {
 	return value_map
 }
 
+// String returns the values for logging, values that are sensitive by naming convention are redacted, to also redact
+// the values listed in a Structure's SensitiveKeys use Redact(sensitiveKeys).String()
 func (cvs ConfigValues) String() string {
-	value_map := make(map[string]any, len(cvs))
+	redacted := cvs.Redact(nil)
+	return fmt.Sprintf("%v", redacted.Value())
+}
+
+// Redact returns a copy of the values with the sensitive values replaced by a hash of the value,
+// the hash is stable so redacted copies can still be compared to detect changes
+func (cvs ConfigValues) Redact(sensitiveKeys []string) ConfigValues {
+	if cvs == nil {
+		return nil
+	}
+
+	result := make(ConfigValues, len(cvs))
 	for k, v := range cvs {
-		value_map[k] = v.Value()
+		if IsSensitiveKey(k, sensitiveKeys) {
+			result[k] = v.redacted()
+		} else {
+			result[k] = *v.DeepCopy()
+		}
 	}
-	return fmt.Sprintf("%v", value_map)
+	return result
 }
 
 func (cvs ConfigValues) Equal(cvs2 ConfigValues) bool {
@@ -52,6 +116,17 @@ func (cvs ConfigValues) Equal(cvs2 ConfigValues) bool {
 	return true
 }
 
+// MergeConfigValues layers the values in order, values in later layers replace values with the same name in earlier layers
+func MergeConfigValues(layers ...ConfigValues) ConfigValues {
+	result := ConfigValues{}
+	for _, layer := range layers {
+		for k, v := range layer {
+			result[k] = *v.DeepCopy()
+		}
+	}
+	return result
+}
+
 func ConfigValuesFromContractor(values map[string]any) ConfigValues {
 	if len(values) == 0 {
 		return map[string]ConfigValue{}
@@ -181,6 +256,18 @@ func (cv ConfigValue) MarshalJSON() ([]byte, error) {
 	return json.Marshal(nil)
 }
 
+// redacted returns a string value containing the keyed hash of the value
+func (cv ConfigValue) redacted() ConfigValue {
+	data, err := json.Marshal(cv)
+	if err != nil { // should not happen, but we never want to leak the value
+		data = []byte{}
+	}
+	mac := hmac.New(sha256.New, redactionKey)
+	mac.Write(data)
+	tmp := RedactedPrefix + hex.EncodeToString(mac.Sum(nil))
+	return ConfigValue{strVal: &tmp}
+}
+
 func (cv ConfigValue) Equal(cv2 ConfigValue) bool {
 	return cmp.Equal(cv.Value(), cv2.Value())
 }
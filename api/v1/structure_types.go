@@ -21,6 +21,35 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ConditionContractorAvailable is False when the operator is unable to authenticate to Contractor, or the
+	// circuit breaker around Contractor calls is open
+	ConditionContractorAvailable = "ContractorAvailable"
+	// ConditionInvalidSpec is True when the controller found the spec invalid, either because it was admitted while
+	// Contractor was unavailable or the webhooks are disabled, the Structure is not acted on until the spec is fixed
+	ConditionInvalidSpec = "InvalidSpec"
+
+	// DeletionProtectionAnnotation set to "true" blocks the Structure from being deleted in any state
+	DeletionProtectionAnnotation = "contractor.t3kton.com/deletion-protection"
+	// ForceDeleteAnnotation is the reason for deleting a Structure who's delete would otherwise be blocked, ie: the
+	// structure has been deleted from contractor
+	ForceDeleteAnnotation = "contractor.t3kton.com/force-delete"
+	// ForceDeleteByAnnotation is set by the webhook to the user that set the ForceDeleteAnnotation
+	ForceDeleteByAnnotation = "contractor.t3kton.com/force-delete-by"
+	// ForceDeleteFinalizer is added by the webhook while the ForceDeleteAnnotation is set, so the controller can
+	// record the ForceDeleted event once the Structure is deleted
+	ForceDeleteFinalizer = "contractor.t3kton.com/force-delete"
+	// UnvalidatedAnnotation is set by the webhook to when the spec was admitted without being validated against
+	// Contractor, the controller removes it once it has validated the spec
+	UnvalidatedAnnotation = "contractor.t3kton.com/unvalidated"
+	// ConsumerConsentAnnotation set to the UID of the bound consumer, by the user that bound it, lets them change or
+	// clear the ConsumerRef, and is required to change the state of a bound Structure to planned.  The controller
+	// removes it once the change has been reconciled so it can not be used again.
+	ConsumerConsentAnnotation = "contractor.t3kton.com/consumer-consent"
+	// BoundByAnnotation is set by the webhook to the user that set the ConsumerRef, only they can consent to changes
+	BoundByAnnotation = "contractor.t3kton.com/bound-by"
+)
+
 // StructureSpec defines the desired state of Structure
 type StructureSpec struct {
 	// +kubebuilder:validation:Required
@@ -36,7 +65,16 @@ type StructureSpec struct {
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
 	ConfigValues ConfigValues `json:"configValues,omitempty"`
-	// ConsumerRef can be used to store information about something that is using this structure.
+	// Profiles is a list of ConfigProfile names, in the same namespace, who's config values are layered in order
+	// under this Structure's ConfigValues, later profiles override earlier ones.
+	// +kubebuilder:validation:Optional
+	Profiles []string `json:"profiles,omitempty"`
+	// SensitiveKeys lists config value names who's values are redacted in the status, logs and events.
+	// Names ending in password, passwd, secret, token or private_key are always treated as sensitive.
+	// +kubebuilder:validation:Optional
+	SensitiveKeys []string `json:"sensitiveKeys,omitempty"`
+	// ConsumerRef can be used to store information about something that is using this structure.  Once set, it can
+	// only be changed by the same consumer, with the consumer's consent, or by a consumer admin.
 	// +kubebuilder:validation:Optional
 	ConsumerRef *corev1.ObjectReference `json:"consumerRef,omitempty"`
 	//
@@ -47,16 +85,39 @@ type StructureSpec struct {
 type StructureStatus struct {
 	State     string `json:"state,omitempty"`
 	BluePrint string `json:"blueprint,omitempty"`
+	// ConfigValues as reported by contractor, sensitive values are replaced with a hash of the value
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
-	ConfigValues        ConfigValues `json:"configValues,omitempty"`
+	ConfigValues ConfigValues `json:"configValues,omitempty"`
+	// EffectiveConfig is the fully merged configuration (site, blueprints, foundation and structure) contractor
+	// will use for this structure, sensitive values are replaced with a hash of the value
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	EffectiveConfig     ConfigValues `json:"effectiveConfig,omitempty"`
 	Job                 *JobStatus   `json:"job,omitempty"`
 	Hostname            string       `json:"hostname,omitempty"`
 	Foundation          string       `json:"foundation,omitempty"`
 	FoundationBluePrint string       `json:"foundationBluePrint,omitempty"`
+	FoundationType      string       `json:"foundationType,omitempty"`
+	// Profiles are the ConfigProfile revisions that the config values on contractor were last built from
+	Profiles []AppliedProfile `json:"profiles,omitempty"`
+	// LastAppliedSpec is the last spec the controller validated, only kept when the webhooks are disabled, sensitive
+	// config values are replaced with a hash of the value
+	LastAppliedSpec *StructureSpec `json:"lastAppliedSpec,omitempty"`
+	// Conditions represent the latest available observations of the Structure's state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// utility job name, utility job result, clear name and result when utility job name is blanked in the spec, the status will be in job Status - will auto clear when the job is complete, also emit events when job is set, started, finishes, etc
 }
 
+// AppliedProfile records the revision of a ConfigProfile that has been applied
+type AppliedProfile struct {
+	Name       string `json:"name"`
+	Generation int64  `json:"generation"`
+}
+
 // JobStatus defines the observed state of the Job
 type JobStatus struct {
 	State            string `json:"state,omitempty"`
@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"testing"
 
@@ -155,6 +157,113 @@ var _ = Describe("Testing Configuration Values", func() {
 	})
 })
 
+var _ = Describe("Testing Sensitive Configuration Values", func() {
+	Context("When Checking Names", func() {
+		It("By Convention", func() {
+			Expect(IsSensitiveKey("password", nil)).To(BeTrue())
+			Expect(IsSensitiveKey("root_password", nil)).To(BeTrue())
+			Expect(IsSensitiveKey(">ipmi_PASSWORD", nil)).To(BeTrue())
+			Expect(IsSensitiveKey("api_token:dev", nil)).To(BeTrue())
+			Expect(IsSensitiveKey("ssh_private_key", nil)).To(BeTrue())
+			Expect(IsSensitiveKey("hostname", nil)).To(BeFalse())
+			Expect(IsSensitiveKey("password_hint", nil)).To(BeFalse())
+		})
+
+		It("By List", func() {
+			Expect(IsSensitiveKey("snmp_community", []string{"snmp_community"})).To(BeTrue())
+			Expect(IsSensitiveKey("<snmp_community:prod", []string{"snmp_community"})).To(BeTrue())
+			Expect(IsSensitiveKey("snmp_community", []string{">snmp_community"})).To(BeTrue())
+			Expect(IsSensitiveKey("snmp_community", []string{"other"})).To(BeFalse())
+		})
+	})
+
+	Context("When Redacting", func() {
+		It("Replaces only the sensitive values", func() {
+			values := ConfigValues{
+				"hostname":       NewConfigValue("bob"),
+				"root_password":  NewConfigValue("hunter2"),
+				"snmp_community": NewConfigValue("public"),
+				"count":          NewConfigValue(3),
+			}
+
+			redacted := values.Redact([]string{"snmp_community"})
+			Expect(redacted).To(HaveLen(4))
+			Expect(redacted["hostname"]).To(Equal(NewConfigValue("bob")))
+			Expect(redacted["count"]).To(Equal(NewConfigValue(3)))
+			Expect(redacted["root_password"].String()).To(HavePrefix(RedactedPrefix))
+			Expect(redacted["snmp_community"].String()).To(HavePrefix(RedactedPrefix))
+			Expect(json.Marshal(redacted)).NotTo(ContainSubstring("hunter2"))
+
+			By("not modifying the origional")
+			Expect(values["root_password"]).To(Equal(NewConfigValue("hunter2")))
+		})
+
+		It("Is stable so changes can still be detected", func() {
+			values := ConfigValues{"root_password": NewConfigValue("hunter2")}
+			same := ConfigValues{"root_password": NewConfigValue("hunter2")}
+			different := ConfigValues{"root_password": NewConfigValue("hunter3")}
+
+			Expect(values.Redact(nil).Equal(same.Redact(nil))).To(BeTrue())
+			Expect(values.Redact(nil).Equal(different.Redact(nil))).To(BeFalse())
+
+		})
+
+		It("Redacts values that only look redacted", func() {
+			fake := ConfigValues{"root_password": NewConfigValue(RedactedPrefix + "1234")}
+			Expect(fake.Redact(nil)["root_password"]).NotTo(Equal(NewConfigValue(RedactedPrefix + "1234")))
+			Expect(fake.Redact(nil)["root_password"].String()).To(HavePrefix(RedactedPrefix))
+
+			By("leaving values that are not sensitive alone")
+			notSensitive := ConfigValues{"hostname": NewConfigValue(RedactedPrefix + "1234")}
+			Expect(notSensitive.Redact(nil)).To(Equal(notSensitive))
+		})
+
+		It("Handles nil", func() {
+			var values ConfigValues
+			Expect(values.Redact(nil)).To(BeNil())
+		})
+
+		It("Is keyed", func() {
+			values := ConfigValues{"root_password": NewConfigValue("hunter2")}
+			defer SetRedactionKey(redactionKey)
+
+			SetRedactionKey([]byte("one"))
+			one := values.Redact(nil)
+			SetRedactionKey([]byte("two"))
+			Expect(values.Redact(nil).Equal(one)).To(BeFalse())
+
+			By("not being the plain hash of the value")
+			hash := sha256.Sum256([]byte(`"hunter2"`))
+			Expect(one["root_password"].String()).NotTo(ContainSubstring(hex.EncodeToString(hash[:])))
+		})
+
+		It("Redacts the values in String()", func() {
+			values := ConfigValues{"root_password": NewConfigValue("hunter2"), "hostname": NewConfigValue("bob")}
+			Expect(values.String()).To(HavePrefix("map[hostname:bob root_password:" + RedactedPrefix))
+			Expect(values.String()).NotTo(ContainSubstring("hunter2"))
+		})
+	})
+})
+
+var _ = Describe("Testing Merging Configuration Values", func() {
+	It("Later layers win", func() {
+		base := ConfigValues{"a": NewConfigValue(1), "b": NewConfigValue("base")}
+		middle := ConfigValues{"b": NewConfigValue("middle"), "c": NewConfigValue(true)}
+		top := ConfigValues{"c": NewConfigValue(false), "d": NewConfigValue([]any{1, 2})}
+
+		result := MergeConfigValues(base, middle, top)
+		Expect(result.Value()).To(Equal(map[string]any{"a": float64(1), "b": "middle", "c": false, "d": []any{float64(1), float64(2)}}))
+
+		By("not modifying the layers")
+		Expect(base.Value()).To(Equal(map[string]any{"a": float64(1), "b": "base"}))
+	})
+
+	It("Handles empty and nil layers", func() {
+		Expect(MergeConfigValues()).To(HaveLen(0))
+		Expect(MergeConfigValues(nil, ConfigValues{"a": NewConfigValue(1)}, nil)).To(HaveLen(1))
+	})
+})
+
 var _ = Describe("Test Job Handeling", func() {
 	// change in state and no existing job
 	// not when state does not change
@@ -20,35 +20,52 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	client "github.com/t3kton/contractor_goclient"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 var config_name_regex = regexp.MustCompile(`^[<>\-~]?[a-zA-Z0-9][a-zA-Z0-9_\-]*(:[a-zA-Z0-9]+)?$`)
 
 // ValidateStructure Validates that the structure is valid
-func (s *Structure) ValidateStructure(ctx context.Context, client *client.Contractor) []error {
+func (s *Structure) ValidateStructure(ctx context.Context, contractor *client.Contractor) []error {
 	var errs []error
 
+	var upstreamStructure *client.BuildingStructure
 	if s.Spec.ID == 0 {
 		errs = append(errs, fmt.Errorf("ID not specified"))
 	} else {
-		_, err := client.BuildingStructureGet(ctx, s.Spec.ID)
+		var err error
+		upstreamStructure, err = contractor.BuildingStructureGet(ctx, s.Spec.ID)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("structure not found"))
 		}
 	}
 
-	if s.Spec.BluePrint == "" { // TODO: We need to make sure the blueprint is valid for the foundation/structure combination also
+	var blueprint *client.BlueprintStructureBluePrint
+	if s.Spec.BluePrint == "" {
 		errs = append(errs, fmt.Errorf("blueprint not specified"))
 	} else {
-		_, err := client.BlueprintStructureBluePrintGet(ctx, s.Spec.BluePrint)
+		var err error
+		blueprint, err = contractor.BlueprintStructureBluePrintGet(ctx, s.Spec.BluePrint)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("blueprint not found"))
 		}
 	}
 
+	if upstreamStructure != nil && blueprint != nil {
+		if err := s.validateFoundationBluePrint(ctx, contractor, upstreamStructure, blueprint); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	if err := validateConfigValues(s.Spec.ConfigValues); err != nil {
 		errs = append(errs, err)
 	}
@@ -64,6 +81,37 @@ func (s *Structure) ValidateChanges(ctx context.Context, client *client.Contract
 		errs = append(errs, err...)
 	}
 
+	return append(errs, s.validateTransition(old)...)
+}
+
+// ValidateOffline runs the checks that do not need contractor, old is nil when the structure is being created.  That
+// the blueprint exists and is compatible with the foundation is left to the controller once contractor is available.
+func (s *Structure) ValidateOffline(old *Structure) []error {
+	var errs []error
+
+	if s.Spec.ID == 0 {
+		errs = append(errs, fmt.Errorf("ID not specified"))
+	}
+
+	if s.Spec.BluePrint == "" {
+		errs = append(errs, fmt.Errorf("blueprint not specified"))
+	}
+
+	if err := validateConfigValues(s.Spec.ConfigValues); err != nil {
+		errs = append(errs, err)
+	}
+
+	if old != nil {
+		errs = append(errs, s.validateTransition(old)...)
+	}
+
+	return errs
+}
+
+// validateTransition checks the changes from old that are not allowed no matter what contractor has
+func (s *Structure) validateTransition(old *Structure) []error {
+	var errs []error
+
 	if s.Spec.ID != old.Spec.ID {
 		errs = append(errs, errors.New("can not change the ID"))
 	}
@@ -87,11 +135,306 @@ func (s *Structure) ValidateChanges(ctx context.Context, client *client.Contract
 	return errs
 }
 
+// ValidateConsumer checks changes to the consumer a structure is bound to, once bound the ConsumerRef can only be
+// changed to refer to the same consumer, unless the user that bound it consents or the change is made by a consumer
+// admin.  Destroying a bound structure always needs the consent of the user that bound it.  requester is the user
+// making the change, old is nil when the structure is being created.
+func (s *Structure) ValidateConsumer(old *Structure, requester string, consumerAdmin bool) []error {
+	var errs []error
+
+	if s.Annotations[BoundByAnnotation] != s.boundBy(old, requester) {
+		errs = append(errs, fmt.Errorf("the '%s' annotation is set to the user that set the consumerRef and can not be changed", BoundByAnnotation))
+	}
+
+	if old == nil || old.Spec.ConsumerRef == nil {
+		return errs
+	}
+	bound := old.Spec.ConsumerRef
+
+	consent := bound.UID != "" && s.Annotations[ConsumerConsentAnnotation] == string(bound.UID) &&
+		requester != "" && requester == old.Annotations[BoundByAnnotation]
+
+	if s.Spec.ConsumerRef == nil || !sameConsumer(bound, s.Spec.ConsumerRef) {
+		if !consent && !consumerAdmin {
+			errs = append(errs, fmt.Errorf("structure is bound to %s '%s', the consumerRef can only be changed by the same consumer, "+
+				"by the user that bound it with the '%s' annotation set to the consumer's uid, or by a consumer admin", bound.Kind, bound.Name, ConsumerConsentAnnotation))
+		}
+	}
+
+	if s.Spec.State == "planned" && old.Spec.State != "planned" && !consent {
+		errs = append(errs, fmt.Errorf("structure is bound to %s '%s', changing the state to planned requires the user that bound it to set the '%s' annotation to the consumer's uid",
+			bound.Kind, bound.Name, ConsumerConsentAnnotation))
+	}
+
+	return errs
+}
+
+// RecordBinding sets the BoundByAnnotation to requester when the structure is being bound to a consumer, keeps the
+// value from old while it stays bound to the same consumer, and removes it when the structure is unbound.  old is nil
+// when the structure is being created.
+func (s *Structure) RecordBinding(old *Structure, requester string) {
+	boundBy := s.boundBy(old, requester)
+	if boundBy == "" {
+		delete(s.Annotations, BoundByAnnotation)
+		return
+	}
+
+	if s.Annotations == nil {
+		s.Annotations = map[string]string{}
+	}
+	s.Annotations[BoundByAnnotation] = boundBy
+}
+
+// RecordForceDelete sets the ForceDeleteByAnnotation to requester when the force-delete annotation is set or it's
+// reason is changed, and holds the ForceDeleteFinalizer while it is set.  Both are removed when the annotation is.
+// old is nil when the structure is being created.
+func (s *Structure) RecordForceDelete(old *Structure, requester string) {
+	reason, force := s.ForceDeleteReason()
+	if !force {
+		delete(s.Annotations, ForceDeleteByAnnotation)
+		controllerutil.RemoveFinalizer(s, ForceDeleteFinalizer)
+		return
+	}
+
+	forceBy := requester
+	if old != nil {
+		if oldReason, oldForce := old.ForceDeleteReason(); oldForce && oldReason == reason {
+			forceBy = old.Annotations[ForceDeleteByAnnotation]
+		}
+	}
+	s.Annotations[ForceDeleteByAnnotation] = forceBy
+
+	// finalizers can not be added once the structure is being deleted
+	if s.DeletionTimestamp.IsZero() {
+		controllerutil.AddFinalizer(s, ForceDeleteFinalizer)
+	}
+}
+
+// boundBy is what the BoundByAnnotation should be after the change from old by requester
+func (s *Structure) boundBy(old *Structure, requester string) string {
+	if s.Spec.ConsumerRef == nil {
+		return ""
+	}
+
+	if old != nil && old.Spec.ConsumerRef != nil && sameConsumer(old.Spec.ConsumerRef, s.Spec.ConsumerRef) {
+		return old.Annotations[BoundByAnnotation]
+	}
+
+	return requester
+}
+
+// sameConsumer is true if the references are to the same object, by UID if the bound reference has one
+func sameConsumer(bound *corev1.ObjectReference, ref *corev1.ObjectReference) bool {
+	if bound.UID != "" {
+		return bound.UID == ref.UID
+	}
+
+	return bound.APIVersion == ref.APIVersion && bound.Kind == ref.Kind && bound.Namespace == ref.Namespace && bound.Name == ref.Name
+}
+
+// ChangeWarnings describes what accepting the structure will cause the operator to do, old is nil when the structure
+// is being created, current is what contractor currently has for the structure.  desired is the config values with
+// the structure's profiles layered under them, on create they are compared to what contractor has, on update the
+// spec is compared to the old spec.
+func (s *Structure) ChangeWarnings(old *Structure, desired ConfigValues, current StructureStatus) []string {
+	var warnings []string
+
+	host := current.Hostname
+	if host == "" {
+		host = "structure " + strconv.Itoa(s.Spec.ID)
+	}
+
+	configChanged := len(ConfigChanges(desired.Redact(s.Spec.SensitiveKeys), current.ConfigValues)) > 0
+	stateChanged := s.Spec.State != "" && s.Spec.State != current.State
+	blueprintChanged := false
+	if old != nil {
+		configChanged = !reflect.DeepEqual(s.Spec.ConfigValues, old.Spec.ConfigValues) || !slices.Equal(s.Spec.Profiles, old.Spec.Profiles)
+		stateChanged = s.Spec.State != old.Spec.State && s.Spec.State != current.State
+		blueprintChanged = s.Spec.BluePrint != old.Spec.BluePrint
+	}
+
+	if stateChanged {
+		switch s.Spec.State {
+		case "built":
+			warnings = append(warnings, fmt.Sprintf("this will start a create job on host '%s'", host))
+		case "planned":
+			warnings = append(warnings, fmt.Sprintf("this will start a destroy job on host '%s'", host))
+		}
+	}
+
+	if configChanged && current.State == "built" {
+		warnings = append(warnings, fmt.Sprintf("config values will be pushed to the built machine '%s'", host))
+	}
+
+	if blueprintChanged {
+		warnings = append(warnings, fmt.Sprintf("blueprint will be changed from '%s' to '%s' on contractor, "+
+			"it will be used when the structure is built", old.Spec.BluePrint, s.Spec.BluePrint))
+	}
+
+	if current.Job != nil && (configChanged || blueprintChanged) {
+		warnings = append(warnings, fmt.Sprintf("structure currently has a running job '%s', the change will be applied after it finishes", current.Job.Script))
+	}
+
+	return warnings
+}
+
+// Action is the next thing the controller does on contractor to make it match a structure
+type Action string
+
+const (
+	// ActionNone is when contractor matches the structure
+	ActionNone Action = ""
+	// ActionWaitForJob is when the structure has a running job, nothing else is done until it finishes
+	ActionWaitForJob Action = "WaitForJob"
+	// ActionPushConfig is when the config values on contractor need to be updated
+	ActionPushConfig Action = "PushConfig"
+	// ActionUpdateBluePrint is when the blueprint needs to be changed and the structure is planned, so it can be
+	// changed without a job
+	ActionUpdateBluePrint Action = "UpdateBluePrint"
+	// ActionCreateJob is when the structure needs to be built
+	ActionCreateJob Action = "CreateJob"
+	// ActionDestroyJob is when the structure needs to be destroyed, either to be planned or so it's blueprint can be changed
+	ActionDestroyJob Action = "DestroyJob"
+	// ActionInvalidState is when the requested state is not one the controller knows how to get to
+	ActionInvalidState Action = "InvalidState"
+)
+
+// NextAction is what the controller does next on contractor to make it match the structure.  desired is the config
+// values with the structure's profiles layered under them, current is what contractor currently has with the config
+// values redacted with the structure's SensitiveKeys.
+func (s *Structure) NextAction(desired ConfigValues, current StructureStatus) Action {
+	if current.Job != nil {
+		return ActionWaitForJob
+	}
+
+	// the current values are redacted, so compare against a redacted copy of the desired values
+	if !desired.Redact(s.Spec.SensitiveKeys).Equal(current.ConfigValues) {
+		return ActionPushConfig
+	}
+
+	if current.State == s.Spec.State && current.BluePrint == s.Spec.BluePrint {
+		return ActionNone
+	}
+
+	// if we are already in planned state, we can update the blueprint, no destroy job needed
+	if current.BluePrint != s.Spec.BluePrint && current.State == "planned" {
+		return ActionUpdateBluePrint
+	}
+
+	switch s.Spec.State {
+	case "built":
+		return ActionCreateJob
+	case "planned":
+		return ActionDestroyJob
+	}
+
+	return ActionInvalidState
+}
+
+// planSteps bounds Plan, each action changes what the next one is, so there are only a few of them
+const planSteps = 6
+
+// Plan describes, in order, what the controller will do on contractor to make it match the structure, assuming each
+// step succeeds.  desired is the config values with the structure's profiles layered under them, current is what
+// contractor currently has with the config values redacted with the structure's SensitiveKeys.
+func (s *Structure) Plan(desired ConfigValues, current StructureStatus) []string {
+	var plan []string
+
+	host := current.Hostname
+	if host == "" {
+		host = "structure " + strconv.Itoa(s.Spec.ID)
+	}
+
+	last := ActionNone
+steps:
+	for range planSteps {
+		action := s.NextAction(desired, current)
+		if action == last { // the controller would repeat it, ie: a create job on a built structure with a different blueprint
+			break
+		}
+		last = action
+
+		switch action {
+		case ActionWaitForJob:
+			plan = append(plan, fmt.Sprintf("wait for the running job '%s' to finish", current.Job.Script))
+			current.Job = nil
+		case ActionPushConfig:
+			redacted := desired.Redact(s.Spec.SensitiveKeys)
+			plan = append(plan, fmt.Sprintf("update the config values of '%s': %s", host, strings.Join(ConfigChanges(redacted, current.ConfigValues), ", ")))
+			current.ConfigValues = redacted
+		case ActionUpdateBluePrint:
+			plan = append(plan, fmt.Sprintf("update the blueprint of '%s' from '%s' to '%s'", host, current.BluePrint, s.Spec.BluePrint))
+			current.BluePrint = s.Spec.BluePrint
+		case ActionCreateJob:
+			plan = append(plan, fmt.Sprintf("start the 'create' job on '%s'", host))
+			current.State = "built"
+		case ActionDestroyJob:
+			plan = append(plan, fmt.Sprintf("start the 'destroy' job on '%s'", host))
+			current.State = "planned"
+		case ActionInvalidState:
+			plan = append(plan, fmt.Sprintf("nothing, '%s' is not a state the structure can be put in", s.Spec.State))
+			break steps
+		default:
+			break steps
+		}
+	}
+
+	if len(plan) == 0 {
+		plan = append(plan, "no changes to contractor")
+	}
+
+	for i := range plan {
+		plan[i] = "plan: " + plan[i]
+	}
+
+	return plan
+}
+
+// ConfigChanges lists the names of the config values that are added, changed or removed going from current to desired
+func ConfigChanges(desired ConfigValues, current ConfigValues) []string {
+	var changes []string
+
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		value, ok := current[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("add '%s'", name))
+		} else if !value.Equal(desired[name]) {
+			changes = append(changes, fmt.Sprintf("change '%s'", name))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(current)) {
+		if _, ok := desired[name]; !ok {
+			changes = append(changes, fmt.Sprintf("remove '%s'", name))
+		}
+	}
+
+	return changes
+}
+
+// CanDelete checks if the structure can be deleted, deletion protection blocks the delete in any state, the
+// force-delete annotation lets through a delete that is otherwise blocked
 func (s *Structure) CanDelete(ctx context.Context) []error {
+	if s.Annotations[DeletionProtectionAnnotation] == "true" {
+		return []error{fmt.Errorf("deletion protection is enabled, remove the '%s' annotation to delete", DeletionProtectionAnnotation)}
+	}
+
+	if reason, force := s.ForceDeleteReason(); force {
+		if reason == "" {
+			return []error{fmt.Errorf("the '%s' annotation requires a reason", ForceDeleteAnnotation)}
+		}
+		return nil
+	}
+
+	return s.DeleteBlockers()
+}
+
+// DeleteBlockers returns the reasons the structure can not be deleted without being forced
+func (s *Structure) DeleteBlockers() []error {
 	var errs []error
 
 	if s.Status.State == "built" || s.Spec.State == "built" {
-		errs = append(errs, errors.New("can not delete Structure that is in planned state"))
+		errs = append(errs, errors.New("can not delete Structure that is in built state"))
 	}
 
 	if s.Status.Job != nil {
@@ -101,6 +444,72 @@ func (s *Structure) CanDelete(ctx context.Context) []error {
 	return errs
 }
 
+// ForceDeleteReason returns the reason given in the force-delete annotation, and if the annotation is set
+func (s *Structure) ForceDeleteReason() (string, bool) {
+	reason, ok := s.Annotations[ForceDeleteAnnotation]
+	return strings.TrimSpace(reason), ok
+}
+
+// validateFoundationBluePrint checks that the structure blueprint lists the blueprint of the structure's foundation as compatible
+func (s *Structure) validateFoundationBluePrint(ctx context.Context, contractor *client.Contractor, structure *client.BuildingStructure, blueprint *client.BlueprintStructureBluePrint) error {
+	if structure.Foundation == nil {
+		return nil
+	}
+
+	foundation, err := contractor.BuildingFoundationGetURI(ctx, *structure.Foundation)
+	if err != nil {
+		return fmt.Errorf("foundation not found")
+	}
+	if foundation.Blueprint == nil {
+		return nil
+	}
+
+	if blueprint.FoundationBlueprintList != nil && slices.Contains(*blueprint.FoundationBlueprintList, *foundation.Blueprint) {
+		return nil
+	}
+
+	compatible, err := compatibleStructureBluePrints(ctx, contractor, *foundation.Blueprint)
+	if err != nil {
+		return fmt.Errorf("blueprint '%s' is not compatible with the foundation blueprint '%s'", s.Spec.BluePrint, uriID(*foundation.Blueprint))
+	}
+
+	return fmt.Errorf("blueprint '%s' is not compatible with the foundation blueprint '%s', compatible structure blueprints: [%s]",
+		s.Spec.BluePrint, uriID(*foundation.Blueprint), strings.Join(compatible, ", "))
+}
+
+// compatibleStructureBluePrints returns the names of the structure blueprints that list the foundation blueprint as
+// compatible, contractor has no filter for this so all the structure blueprints are listed
+func compatibleStructureBluePrints(ctx context.Context, contractor *client.Contractor, foundationBluePrint string) ([]string, error) {
+	blueprints, err := contractor.BlueprintStructureBluePrintList(ctx, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for blueprint := range blueprints {
+		if blueprint.FoundationBlueprintList != nil && slices.Contains(*blueprint.FoundationBlueprintList, foundationBluePrint) {
+			result = append(result, uriID(blueprint.GetURI()))
+		}
+	}
+
+	return result, nil
+}
+
+// uriID extracts the id from a contractor uri, ie: "/api/v1/BluePrint/FoundationBluePrint:generic-linux:" -> "generic-linux"
+func uriID(uri string) string {
+	parts := strings.Split(uri, ":")
+	if len(parts) != 3 {
+		return uri
+	}
+
+	return parts[1]
+}
+
+// Validate checks the names of the profile's config values, the same as a Structure's config values
+func (p *ConfigProfile) Validate() error {
+	return validateConfigValues(p.Spec.ConfigValues)
+}
+
 func validateConfigValues(configurationValues ConfigValues) error {
 	for name := range configurationValues {
 		if !config_name_regex.MatchString(name) {
@@ -0,0 +1,254 @@
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Testing Change Warnings", func() {
+	var old, structure *Structure
+
+	BeforeEach(func() {
+		old = &Structure{
+			Spec: StructureSpec{
+				ID:           42,
+				State:        "built",
+				BluePrint:    "test-structure-base",
+				ConfigValues: ConfigValues{"a": NewConfigValue("b")},
+			},
+			Status: StructureStatus{State: "built", BluePrint: "test-structure-base", Hostname: "web01"},
+		}
+		structure = old.DeepCopy()
+	})
+
+	It("Has nothing to say when nothing changes", func() {
+		Expect(structure.ChangeWarnings(old, structure.Spec.ConfigValues, old.Status)).To(BeEmpty())
+	})
+
+	It("Warns about jobs started by changing the state", func() {
+		structure.Spec.State = "planned"
+		Expect(structure.ChangeWarnings(old, structure.Spec.ConfigValues, old.Status)).To(Equal([]string{"this will start a destroy job on host 'web01'"}))
+
+		By("creating a structure that is not built yet")
+		structure.Spec.State = "built"
+		Expect(structure.ChangeWarnings(nil, structure.Spec.ConfigValues, StructureStatus{State: "planned"})).To(ContainElement("this will start a create job on host 'structure 42'"))
+	})
+
+	It("Warns about config values pushed to a built machine", func() {
+		structure.Spec.ConfigValues["a"] = NewConfigValue("c")
+		Expect(structure.ChangeWarnings(old, structure.Spec.ConfigValues, old.Status)).To(Equal([]string{"config values will be pushed to the built machine 'web01'"}))
+
+		By("not warning while planned")
+		old.Status.State = "planned"
+		Expect(structure.ChangeWarnings(old, structure.Spec.ConfigValues, old.Status)).To(BeEmpty())
+	})
+
+	It("Warns about config values pushed on create only if they differ from contractor's", func() {
+		structure.Spec.SensitiveKeys = []string{"password"}
+		structure.Spec.ConfigValues["password"] = NewConfigValue("secret")
+		current := StructureStatus{State: "built", Hostname: "web01", ConfigValues: structure.Spec.ConfigValues.Redact(structure.Spec.SensitiveKeys)}
+		Expect(structure.ChangeWarnings(nil, structure.Spec.ConfigValues, current)).To(BeEmpty())
+
+		By("including the values from profiles")
+		desired := MergeConfigValues(ConfigValues{"b": NewConfigValue("c")}, structure.Spec.ConfigValues)
+		Expect(structure.ChangeWarnings(nil, desired, current)).To(Equal([]string{"config values will be pushed to the built machine 'web01'"}))
+	})
+
+	It("Warns that changes wait for a running job", func() {
+		old.Status.Job = &JobStatus{Script: "create"}
+		structure.Spec.Profiles = []string{"base"}
+		Expect(structure.ChangeWarnings(old, structure.Spec.ConfigValues, old.Status)).To(Equal([]string{
+			"config values will be pushed to the built machine 'web01'",
+			"structure currently has a running job 'create', the change will be applied after it finishes",
+		}))
+	})
+})
+
+var _ = Describe("Testing Offline Validation", func() {
+	It("Checks what it can without contractor", func() {
+		structure := &Structure{Spec: StructureSpec{ID: 42, State: "planned", BluePrint: "test-structure-base"}}
+		Expect(structure.ValidateOffline(nil)).To(BeEmpty())
+
+		structure.Spec.BluePrint = ""
+		structure.Spec.ConfigValues = ConfigValues{"bad name": NewConfigValue("b")}
+		Expect(structure.ValidateOffline(nil)).To(ConsistOf(
+			MatchError("blueprint not specified"),
+			MatchError("invalid configuration value name 'bad name'"),
+		))
+	})
+
+	It("Checks the transition from the old structure", func() {
+		old := &Structure{
+			Spec:   StructureSpec{ID: 42, State: "planned", BluePrint: "test-structure-base"},
+			Status: StructureStatus{State: "planned", Job: &JobStatus{Script: "destroy"}},
+		}
+		structure := old.DeepCopy()
+		structure.Spec.State = "built"
+		Expect(structure.ValidateOffline(old)).To(ConsistOf(MatchError("can not change the State while there is a Job")))
+	})
+})
+
+var _ = Describe("Testing Consumer Binding", func() {
+	const binder = "system:serviceaccount:machines:machine-controller"
+	var old, structure *Structure
+
+	BeforeEach(func() {
+		old = &Structure{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{BoundByAnnotation: binder}},
+			Spec: StructureSpec{
+				ID:          42,
+				State:       "built",
+				BluePrint:   "test-structure-base",
+				ConsumerRef: &corev1.ObjectReference{Kind: "Machine", Name: "web01", UID: "1234"},
+			},
+		}
+		structure = old.DeepCopy()
+	})
+
+	It("Lets anyone bind an unbound structure, recording who bound it", func() {
+		old.Spec.ConsumerRef = nil
+		delete(old.Annotations, BoundByAnnotation)
+		delete(structure.Annotations, BoundByAnnotation)
+		Expect(structure.ValidateConsumer(old, "bob", false)).To(ConsistOf(
+			MatchError("the 'contractor.t3kton.com/bound-by' annotation is set to the user that set the consumerRef and can not be changed"),
+		))
+
+		structure.RecordBinding(old, "bob")
+		Expect(structure.Annotations).To(HaveKeyWithValue(BoundByAnnotation, "bob"))
+		Expect(structure.ValidateConsumer(old, "bob", false)).To(BeEmpty())
+
+		By("recording who bound it on create")
+		structure.RecordBinding(nil, "carol")
+		Expect(structure.Annotations).To(HaveKeyWithValue(BoundByAnnotation, "carol"))
+		Expect(structure.ValidateConsumer(nil, "carol", false)).To(BeEmpty())
+		Expect(structure.ValidateConsumer(nil, "bob", false)).To(HaveLen(1))
+	})
+
+	It("Lets the same consumer update it's reference", func() {
+		structure.Spec.ConsumerRef.ResourceVersion = "7"
+		structure.RecordBinding(old, "bob")
+		Expect(structure.Annotations).To(HaveKeyWithValue(BoundByAnnotation, binder))
+		Expect(structure.ValidateConsumer(old, "bob", false)).To(BeEmpty())
+	})
+
+	It("Does not let the bound-by annotation be changed", func() {
+		structure.Annotations[BoundByAnnotation] = "bob"
+		Expect(structure.ValidateConsumer(old, "bob", false)).To(ConsistOf(
+			MatchError("the 'contractor.t3kton.com/bound-by' annotation is set to the user that set the consumerRef and can not be changed"),
+		))
+	})
+
+	It("Needs consent from the user that bound it or a consumer admin to change or clear the reference", func() {
+		structure.Spec.ConsumerRef = &corev1.ObjectReference{Kind: "Machine", Name: "web01", UID: "5678"}
+		structure.RecordBinding(old, "bob")
+		Expect(structure.ValidateConsumer(old, "bob", false)).To(ConsistOf(MatchError("structure is bound to Machine 'web01', the consumerRef can only be " +
+			"changed by the same consumer, by the user that bound it with the 'contractor.t3kton.com/consumer-consent' annotation set to the consumer's uid, " +
+			"or by a consumer admin")))
+		Expect(structure.ValidateConsumer(old, "bob", true)).To(BeEmpty())
+
+		structure.Spec.ConsumerRef = nil
+		structure.RecordBinding(old, binder)
+		Expect(structure.Annotations).NotTo(HaveKey(BoundByAnnotation))
+		Expect(structure.ValidateConsumer(old, binder, false)).To(HaveLen(1))
+
+		By("not accepting consent from someone else, even with the consumer's uid")
+		structure.Annotations[ConsumerConsentAnnotation] = "1234"
+		Expect(structure.ValidateConsumer(old, "bob", false)).To(HaveLen(1))
+
+		Expect(structure.ValidateConsumer(old, binder, false)).To(BeEmpty())
+	})
+
+	It("Needs consent to destroy a bound structure", func() {
+		structure.Spec.State = "planned"
+		Expect(structure.ValidateConsumer(old, binder, true)).To(ConsistOf(MatchError("structure is bound to Machine 'web01', changing the state to " +
+			"planned requires the user that bound it to set the 'contractor.t3kton.com/consumer-consent' annotation to the consumer's uid")))
+
+		structure.Annotations[ConsumerConsentAnnotation] = "5678"
+		Expect(structure.ValidateConsumer(old, binder, false)).To(HaveLen(1))
+
+		structure.Annotations[ConsumerConsentAnnotation] = "1234"
+		Expect(structure.ValidateConsumer(old, "bob", false)).To(HaveLen(1))
+		Expect(structure.ValidateConsumer(old, binder, false)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Testing Plans", func() {
+	var structure *Structure
+	var current StructureStatus
+
+	BeforeEach(func() {
+		structure = &Structure{Spec: StructureSpec{
+			ID:           42,
+			State:        "planned",
+			BluePrint:    "test-structure-base",
+			ConfigValues: ConfigValues{"a": NewConfigValue("b")},
+		}}
+		current = StructureStatus{State: "planned", BluePrint: "test-structure-base", Hostname: "web01", ConfigValues: ConfigValues{"a": NewConfigValue("b")}}
+	})
+
+	It("Has nothing to do when contractor matches", func() {
+		Expect(structure.Plan(structure.Spec.ConfigValues, current)).To(Equal([]string{"plan: no changes to contractor"}))
+	})
+
+	It("Lists the steps in the order the controller does them", func() {
+		structure.Spec.State = "built"
+		structure.Spec.BluePrint = "web-base"
+		desired := ConfigValues{"a": NewConfigValue("c"), "d": NewConfigValue(1)}
+		current.ConfigValues["e"] = NewConfigValue(true)
+		current.Job = &JobStatus{Script: "destroy"}
+
+		Expect(structure.Plan(desired, current)).To(Equal([]string{
+			"plan: wait for the running job 'destroy' to finish",
+			"plan: update the config values of 'web01': change 'a', add 'd', remove 'e'",
+			"plan: update the blueprint of 'web01' from 'test-structure-base' to 'web-base'",
+			"plan: start the 'create' job on 'web01'",
+		}))
+	})
+
+	It("Compares sensitive values redacted", func() {
+		structure.Spec.SensitiveKeys = []string{"a"}
+		current.ConfigValues = ConfigValues{"a": NewConfigValue("b")}.Redact([]string{"a"})
+		Expect(structure.Plan(structure.Spec.ConfigValues, current)).To(Equal([]string{"plan: no changes to contractor"}))
+	})
+
+	It("Updates the blueprint after destroying the structure", func() {
+		structure.Spec.BluePrint = "web-base"
+		current.State = "built"
+
+		Expect(structure.NextAction(structure.Spec.ConfigValues, current)).To(Equal(ActionDestroyJob))
+		Expect(structure.Plan(structure.Spec.ConfigValues, current)).To(Equal([]string{
+			"plan: start the 'destroy' job on 'web01'",
+			"plan: update the blueprint of 'web01' from 'test-structure-base' to 'web-base'",
+		}))
+	})
+
+	It("Does not repeat a job the controller would start again", func() {
+		structure.Spec.State = "built"
+		structure.Spec.BluePrint = "web-base"
+		current.State = "built"
+
+		Expect(structure.Plan(structure.Spec.ConfigValues, current)).To(Equal([]string{"plan: start the 'create' job on 'web01'"}))
+	})
+
+	It("Decides the next action the same as the plan", func() {
+		Expect(structure.NextAction(structure.Spec.ConfigValues, current)).To(Equal(ActionNone))
+
+		current.Job = &JobStatus{Script: "create"}
+		Expect(structure.NextAction(ConfigValues{"a": NewConfigValue("c")}, current)).To(Equal(ActionWaitForJob))
+
+		current.Job = nil
+		Expect(structure.NextAction(ConfigValues{"a": NewConfigValue("c")}, current)).To(Equal(ActionPushConfig))
+
+		structure.Spec.BluePrint = "web-base"
+		Expect(structure.NextAction(structure.Spec.ConfigValues, current)).To(Equal(ActionUpdateBluePrint))
+
+		structure.Spec.State = "built"
+		structure.Spec.BluePrint = "test-structure-base"
+		Expect(structure.NextAction(structure.Spec.ConfigValues, current)).To(Equal(ActionCreateJob))
+
+		structure.Spec.State = "unknown"
+		Expect(structure.NextAction(structure.Spec.ConfigValues, current)).To(Equal(ActionInvalidState))
+	})
+})
@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StructurePolicySpec restricts what the Structures in the bound namespaces can point at.  Every policy bound to a
+// namespace must be satisfied, a field that is left empty does not restrict anything.
+type StructurePolicySpec struct {
+	// Namespaces the policy is bound to by name
+	// +kubebuilder:validation:Optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelector binds the policy to the namespaces with matching labels
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Sites are the Contractor site names the Structure's foundation may be in
+	// +kubebuilder:validation:Optional
+	Sites []string `json:"sites,omitempty"`
+	// FoundationTypes are the Contractor foundation types the Structure may be on, ie: "Manual", "VCenter"
+	// +kubebuilder:validation:Optional
+	FoundationTypes []string `json:"foundationTypes,omitempty"`
+	// BluePrints are shell glob patterns, ie: "web-*", of the structure blueprints that may be used
+	// +kubebuilder:validation:Optional
+	BluePrints []string `json:"blueprints,omitempty"`
+	// IDRanges are the Contractor structure IDs that may be used
+	// +kubebuilder:validation:Optional
+	IDRanges []IDRange `json:"idRanges,omitempty"`
+	// StateTransitions are the changes of the requested state that are permitted
+	// +kubebuilder:validation:Optional
+	StateTransitions []StateTransition `json:"stateTransitions,omitempty"`
+}
+
+// IDRange is an inclusive range of Contractor structure IDs
+type IDRange struct {
+	// +kubebuilder:validation:Minimum=1
+	Min int `json:"min"`
+	// +kubebuilder:validation:Minimum=1
+	Max int `json:"max"`
+}
+
+// StateTransition is a change of the requested state
+type StateTransition struct {
+	// +kubebuilder:validation:Enum=planned;built
+	From string `json:"from"`
+	// +kubebuilder:validation:Enum=planned;built
+	To string `json:"to"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// StructurePolicy is the Schema for the structurepolicies API
+type StructurePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StructurePolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// StructurePolicyList contains a list of StructurePolicy
+type StructurePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StructurePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StructurePolicy{}, &StructurePolicyList{})
+}
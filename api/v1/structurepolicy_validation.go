@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PolicyFoundation is the site and type of a Structure's foundation on contractor, as checked by StructurePolicies
+type PolicyFoundation struct {
+	Site string
+	Type string
+}
+
+// Binds returns true if the policy applies to the namespace
+func (p *StructurePolicy) Binds(namespace *corev1.Namespace) (bool, error) {
+	if slices.Contains(p.Spec.Namespaces, namespace.Name) {
+		return true, nil
+	}
+
+	if p.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("StructurePolicy '%s' has an invalid namespaceSelector: %w", p.Name, err)
+	}
+
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// NeedsFoundation is true if the policy restricts the site or type of the foundation
+func (p *StructurePolicy) NeedsFoundation() bool {
+	return len(p.Spec.Sites) > 0 || len(p.Spec.FoundationTypes) > 0
+}
+
+// CheckStructure returns the ways the structure violates the policy.  fromState is the state the structure is changing
+// from, blank if it is not known, clearing the requested state is a change to blank.  foundation is only used if
+// NeedsFoundation is true.
+func (p *StructurePolicy) CheckStructure(s *Structure, fromState string, foundation *PolicyFoundation) []error {
+	var errs []error
+
+	if len(p.Spec.IDRanges) > 0 && !slices.ContainsFunc(p.Spec.IDRanges, func(r IDRange) bool { return s.Spec.ID >= r.Min && s.Spec.ID <= r.Max }) {
+		ranges := make([]string, len(p.Spec.IDRanges))
+		for i, r := range p.Spec.IDRanges {
+			ranges[i] = strconv.Itoa(r.Min) + "-" + strconv.Itoa(r.Max)
+		}
+		errs = append(errs, fmt.Errorf("StructurePolicy '%s': ID %d is not in the allowed ranges [%s]", p.Name, s.Spec.ID, strings.Join(ranges, ", ")))
+	}
+
+	if len(p.Spec.BluePrints) > 0 && s.Spec.BluePrint != "" && !slices.ContainsFunc(p.Spec.BluePrints, func(pattern string) bool {
+		match, err := path.Match(pattern, s.Spec.BluePrint)
+		return err == nil && match
+	}) {
+		errs = append(errs, fmt.Errorf("StructurePolicy '%s': blueprint '%s' does not match any of [%s]", p.Name, s.Spec.BluePrint, strings.Join(p.Spec.BluePrints, ", ")))
+	}
+
+	if p.NeedsFoundation() && foundation != nil {
+		if len(p.Spec.Sites) > 0 && !slices.Contains(p.Spec.Sites, foundation.Site) {
+			errs = append(errs, fmt.Errorf("StructurePolicy '%s': site '%s' is not one of [%s]", p.Name, foundation.Site, strings.Join(p.Spec.Sites, ", ")))
+		}
+
+		if len(p.Spec.FoundationTypes) > 0 && !slices.Contains(p.Spec.FoundationTypes, foundation.Type) {
+			errs = append(errs, fmt.Errorf("StructurePolicy '%s': foundation type '%s' is not one of [%s]", p.Name, foundation.Type, strings.Join(p.Spec.FoundationTypes, ", ")))
+		}
+	}
+
+	if len(p.Spec.StateTransitions) > 0 && fromState != "" && fromState != s.Spec.State &&
+		!slices.Contains(p.Spec.StateTransitions, StateTransition{From: fromState, To: s.Spec.State}) {
+		errs = append(errs, fmt.Errorf("StructurePolicy '%s': changing the state from '%s' to '%s' is not permitted", p.Name, fromState, s.Spec.State))
+	}
+
+	return errs
+}
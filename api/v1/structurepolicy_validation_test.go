@@ -0,0 +1,80 @@
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Testing Structure Policies", func() {
+	var policy *StructurePolicy
+	var structure *Structure
+
+	BeforeEach(func() {
+		policy = &StructurePolicy{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+		structure = &Structure{Spec: StructureSpec{ID: 42, State: "built", BluePrint: "web-base"}}
+	})
+
+	It("Binds namespaces by name or selector", func() {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}}
+		Expect(policy.Binds(namespace)).To(BeFalse())
+
+		policy.Spec.Namespaces = []string{"team-a"}
+		Expect(policy.Binds(namespace)).To(BeTrue())
+
+		policy.Spec.Namespaces = nil
+		policy.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}
+		Expect(policy.Binds(namespace)).To(BeFalse())
+
+		policy.Spec.NamespaceSelector.MatchLabels["team"] = "a"
+		Expect(policy.Binds(namespace)).To(BeTrue())
+	})
+
+	It("Does not restrict anything when empty", func() {
+		Expect(policy.NeedsFoundation()).To(BeFalse())
+		Expect(policy.CheckStructure(structure, "planned", nil)).To(BeEmpty())
+	})
+
+	It("Checks the site and foundation type", func() {
+		policy.Spec.Sites = []string{"site1"}
+		policy.Spec.FoundationTypes = []string{"Manual"}
+		Expect(policy.NeedsFoundation()).To(BeTrue())
+		Expect(policy.CheckStructure(structure, "", &PolicyFoundation{Site: "site1", Type: "Manual"})).To(BeEmpty())
+
+		errs := policy.CheckStructure(structure, "", &PolicyFoundation{Site: "site2", Type: "VCenter"})
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0]).To(MatchError("StructurePolicy 'team-a': site 'site2' is not one of [site1]"))
+		Expect(errs[1]).To(MatchError("StructurePolicy 'team-a': foundation type 'VCenter' is not one of [Manual]"))
+	})
+
+	It("Checks the blueprint and ID", func() {
+		policy.Spec.BluePrints = []string{"db-*", "web-*"}
+		policy.Spec.IDRanges = []IDRange{{Min: 1, Max: 10}, {Min: 40, Max: 49}}
+		Expect(policy.CheckStructure(structure, "", nil)).To(BeEmpty())
+
+		structure.Spec.ID = 50
+		structure.Spec.BluePrint = "mail-base"
+		errs := policy.CheckStructure(structure, "", nil)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0]).To(MatchError("StructurePolicy 'team-a': ID 50 is not in the allowed ranges [1-10, 40-49]"))
+		Expect(errs[1]).To(MatchError("StructurePolicy 'team-a': blueprint 'mail-base' does not match any of [db-*, web-*]"))
+	})
+
+	It("Checks state transitions", func() {
+		policy.Spec.StateTransitions = []StateTransition{{From: "planned", To: "built"}}
+		Expect(policy.CheckStructure(structure, "planned", nil)).To(BeEmpty())
+		Expect(policy.CheckStructure(structure, "built", nil)).To(BeEmpty())
+
+		structure.Spec.State = "planned"
+		Expect(policy.CheckStructure(structure, "built", nil)).To(ConsistOf(
+			MatchError("StructurePolicy 'team-a': changing the state from 'built' to 'planned' is not permitted"),
+		))
+		By("treating clearing the state as a transition")
+		structure.Spec.State = ""
+		Expect(policy.CheckStructure(structure, "built", nil)).To(ConsistOf(
+			MatchError("StructurePolicy 'team-a': changing the state from 'built' to '' is not permitted"),
+		))
+		Expect(policy.CheckStructure(structure, "", nil)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,72 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StructureQuotaSpec limits the number of built Structures in the namespace.  A Structure counts as built if it's
+// requested state is built, or it has not been destroyed yet.
+type StructureQuotaSpec struct {
+	// Hard is the maximum number of built Structures in the namespace
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	Hard *int32 `json:"hard,omitempty"`
+	// BluePrints is the maximum number of built Structures by structure blueprint
+	// +kubebuilder:validation:Optional
+	BluePrints map[string]int32 `json:"blueprints,omitempty"`
+	// FoundationTypes is the maximum number of built Structures by foundation type, ie: "Manual", "VCenter"
+	// +kubebuilder:validation:Optional
+	FoundationTypes map[string]int32 `json:"foundationTypes,omitempty"`
+}
+
+// StructureQuotaStatus is the number of built Structures in the namespace
+type StructureQuotaStatus struct {
+	Used int32 `json:"used"`
+	// BluePrints is the number of built Structures by the structure blueprints limited in the spec
+	BluePrints map[string]int32 `json:"blueprints,omitempty"`
+	// FoundationTypes is the number of built Structures by the foundation types limited in the spec
+	FoundationTypes map[string]int32 `json:"foundationTypes,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Used",type=integer,JSONPath=`.status.used`
+// +kubebuilder:printcolumn:name="Hard",type=integer,JSONPath=`.spec.hard`
+
+// StructureQuota is the Schema for the structurequotas API
+type StructureQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StructureQuotaSpec   `json:"spec,omitempty"`
+	Status StructureQuotaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// StructureQuotaList contains a list of StructureQuota
+type StructureQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StructureQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StructureQuota{}, &StructureQuotaList{})
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+)
+
+// ConsumesQuota is true if the structure counts as built for StructureQuotas, it's requested state is built or
+// it has not been destroyed yet
+func (s *Structure) ConsumesQuota() bool {
+	return s.Spec.State == "built" || s.Status.State == "built"
+}
+
+// quotaBluePrint is the blueprint the structure counts against, the requested blueprint or, if none was requested, the
+// blueprint it has on contractor
+func (s *Structure) quotaBluePrint() string {
+	if s.Spec.BluePrint != "" {
+		return s.Spec.BluePrint
+	}
+	return s.Status.BluePrint
+}
+
+// Usage counts the built structures against the quota, the foundation type is taken from the structure's status
+func (q *StructureQuota) Usage(structures []Structure) StructureQuotaStatus {
+	usage := StructureQuotaStatus{}
+	if len(q.Spec.BluePrints) > 0 {
+		usage.BluePrints = make(map[string]int32, len(q.Spec.BluePrints))
+		for name := range q.Spec.BluePrints {
+			usage.BluePrints[name] = 0
+		}
+	}
+	if len(q.Spec.FoundationTypes) > 0 {
+		usage.FoundationTypes = make(map[string]int32, len(q.Spec.FoundationTypes))
+		for name := range q.Spec.FoundationTypes {
+			usage.FoundationTypes[name] = 0
+		}
+	}
+
+	for i := range structures {
+		if !structures[i].ConsumesQuota() {
+			continue
+		}
+
+		usage.Used++
+		if _, ok := usage.BluePrints[structures[i].quotaBluePrint()]; ok {
+			usage.BluePrints[structures[i].quotaBluePrint()]++
+		}
+		if _, ok := usage.FoundationTypes[structures[i].Status.FoundationType]; ok {
+			usage.FoundationTypes[structures[i].Status.FoundationType]++
+		}
+	}
+
+	return usage
+}
+
+// Admit returns the ways building the structure would exceed the quota, usage must not include the structure
+func (q *StructureQuota) Admit(usage StructureQuotaStatus, s *Structure, foundationType string) []error {
+	var errs []error
+
+	if q.Spec.Hard != nil && usage.Used+1 > *q.Spec.Hard {
+		errs = append(errs, fmt.Errorf("exceeded StructureQuota '%s': built structures, used %d of %d", q.Name, usage.Used, *q.Spec.Hard))
+	}
+
+	blueprint := s.quotaBluePrint()
+	if hard, ok := q.Spec.BluePrints[blueprint]; ok && usage.BluePrints[blueprint]+1 > hard {
+		errs = append(errs, fmt.Errorf("exceeded StructureQuota '%s': blueprint '%s', used %d of %d", q.Name, blueprint, usage.BluePrints[blueprint], hard))
+	}
+
+	if hard, ok := q.Spec.FoundationTypes[foundationType]; ok && usage.FoundationTypes[foundationType]+1 > hard {
+		errs = append(errs, fmt.Errorf("exceeded StructureQuota '%s': foundation type '%s', used %d of %d", q.Name, foundationType, usage.FoundationTypes[foundationType], hard))
+	}
+
+	return errs
+}
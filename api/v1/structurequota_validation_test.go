@@ -0,0 +1,55 @@
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Testing Structure Quotas", func() {
+	It("Admits structures up to the limits", func() {
+		hard := int32(3)
+		quota := &StructureQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+			Spec: StructureQuotaSpec{
+				Hard:            &hard,
+				BluePrints:      map[string]int32{"gpu-worker": 1},
+				FoundationTypes: map[string]int32{"VCenter": 2},
+			},
+		}
+		structures := []Structure{
+			{Spec: StructureSpec{State: "built", BluePrint: "gpu-worker"}, Status: StructureStatus{FoundationType: "VCenter"}},
+			{Spec: StructureSpec{State: "planned", BluePrint: "web"}, Status: StructureStatus{State: "planned", FoundationType: "VCenter"}},
+		}
+		structure := &Structure{Spec: StructureSpec{State: "built", BluePrint: "web"}}
+
+		usage := quota.Usage(structures)
+		Expect(usage).To(Equal(StructureQuotaStatus{Used: 1, BluePrints: map[string]int32{"gpu-worker": 1}, FoundationTypes: map[string]int32{"VCenter": 1}}))
+		Expect(quota.Admit(usage, structure, "VCenter")).To(BeEmpty())
+
+		structure.Spec.BluePrint = "gpu-worker"
+		structures[1].Spec.State = "built"
+		usage = quota.Usage(structures)
+		Expect(quota.Admit(usage, structure, "VCenter")).To(ConsistOf(
+			MatchError("exceeded StructureQuota 'team-a': blueprint 'gpu-worker', used 1 of 1"),
+			MatchError("exceeded StructureQuota 'team-a': foundation type 'VCenter', used 2 of 2"),
+		))
+	})
+
+	It("Counts the contractor blueprint when no blueprint is requested", func() {
+		quota := &StructureQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+			Spec:       StructureQuotaSpec{BluePrints: map[string]int32{"gpu-worker": 1}},
+		}
+		structures := []Structure{
+			{Spec: StructureSpec{State: "built"}, Status: StructureStatus{State: "built", BluePrint: "gpu-worker"}},
+		}
+		structure := &Structure{Spec: StructureSpec{State: "built"}, Status: StructureStatus{BluePrint: "gpu-worker"}}
+
+		usage := quota.Usage(structures)
+		Expect(usage.BluePrints).To(Equal(map[string]int32{"gpu-worker": 1}))
+		Expect(quota.Admit(usage, structure, "")).To(ConsistOf(
+			MatchError("exceeded StructureQuota 'team-a': blueprint 'gpu-worker', used 1 of 1"),
+		))
+	})
+})
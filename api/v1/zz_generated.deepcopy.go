@@ -22,9 +22,105 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedProfile) DeepCopyInto(out *AppliedProfile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedProfile.
+func (in *AppliedProfile) DeepCopy() *AppliedProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigProfile) DeepCopyInto(out *ConfigProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigProfile.
+func (in *ConfigProfile) DeepCopy() *ConfigProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigProfileList) DeepCopyInto(out *ConfigProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ConfigProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigProfileList.
+func (in *ConfigProfileList) DeepCopy() *ConfigProfileList {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigProfileList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigProfileSpec) DeepCopyInto(out *ConfigProfileSpec) {
+	*out = *in
+	if in.ConfigValues != nil {
+		in, out := &in.ConfigValues, &out.ConfigValues
+		*out = make(ConfigValues, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigProfileSpec.
+func (in *ConfigProfileSpec) DeepCopy() *ConfigProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigValue) DeepCopyInto(out *ConfigValue) {
 	*out = *in
@@ -90,6 +186,21 @@ func (in ConfigValues) DeepCopy() ConfigValues {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IDRange) DeepCopyInto(out *IDRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IDRange.
+func (in *IDRange) DeepCopy() *IDRange {
+	if in == nil {
+		return nil
+	}
+	out := new(IDRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JobStatus) DeepCopyInto(out *JobStatus) {
 	*out = *in
@@ -105,6 +216,36 @@ func (in *JobStatus) DeepCopy() *JobStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyFoundation) DeepCopyInto(out *PolicyFoundation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyFoundation.
+func (in *PolicyFoundation) DeepCopy() *PolicyFoundation {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyFoundation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateTransition) DeepCopyInto(out *StateTransition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateTransition.
+func (in *StateTransition) DeepCopy() *StateTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(StateTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Structure) DeepCopyInto(out *Structure) {
 	*out = *in
@@ -164,6 +305,236 @@ func (in *StructureList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructurePolicy) DeepCopyInto(out *StructurePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructurePolicy.
+func (in *StructurePolicy) DeepCopy() *StructurePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(StructurePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StructurePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructurePolicyList) DeepCopyInto(out *StructurePolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StructurePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructurePolicyList.
+func (in *StructurePolicyList) DeepCopy() *StructurePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(StructurePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StructurePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructurePolicySpec) DeepCopyInto(out *StructurePolicySpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sites != nil {
+		in, out := &in.Sites, &out.Sites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FoundationTypes != nil {
+		in, out := &in.FoundationTypes, &out.FoundationTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BluePrints != nil {
+		in, out := &in.BluePrints, &out.BluePrints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IDRanges != nil {
+		in, out := &in.IDRanges, &out.IDRanges
+		*out = make([]IDRange, len(*in))
+		copy(*out, *in)
+	}
+	if in.StateTransitions != nil {
+		in, out := &in.StateTransitions, &out.StateTransitions
+		*out = make([]StateTransition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructurePolicySpec.
+func (in *StructurePolicySpec) DeepCopy() *StructurePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StructurePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructureQuota) DeepCopyInto(out *StructureQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructureQuota.
+func (in *StructureQuota) DeepCopy() *StructureQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(StructureQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StructureQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructureQuotaList) DeepCopyInto(out *StructureQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StructureQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructureQuotaList.
+func (in *StructureQuotaList) DeepCopy() *StructureQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(StructureQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StructureQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructureQuotaSpec) DeepCopyInto(out *StructureQuotaSpec) {
+	*out = *in
+	if in.Hard != nil {
+		in, out := &in.Hard, &out.Hard
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BluePrints != nil {
+		in, out := &in.BluePrints, &out.BluePrints
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FoundationTypes != nil {
+		in, out := &in.FoundationTypes, &out.FoundationTypes
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructureQuotaSpec.
+func (in *StructureQuotaSpec) DeepCopy() *StructureQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StructureQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructureQuotaStatus) DeepCopyInto(out *StructureQuotaStatus) {
+	*out = *in
+	if in.BluePrints != nil {
+		in, out := &in.BluePrints, &out.BluePrints
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FoundationTypes != nil {
+		in, out := &in.FoundationTypes, &out.FoundationTypes
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructureQuotaStatus.
+func (in *StructureQuotaStatus) DeepCopy() *StructureQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StructureQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StructureSpec) DeepCopyInto(out *StructureSpec) {
 	*out = *in
@@ -174,6 +545,16 @@ func (in *StructureSpec) DeepCopyInto(out *StructureSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SensitiveKeys != nil {
+		in, out := &in.SensitiveKeys, &out.SensitiveKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ConsumerRef != nil {
 		in, out := &in.ConsumerRef, &out.ConsumerRef
 		*out = new(corev1.ObjectReference)
@@ -201,11 +582,35 @@ func (in *StructureStatus) DeepCopyInto(out *StructureStatus) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.EffectiveConfig != nil {
+		in, out := &in.EffectiveConfig, &out.EffectiveConfig
+		*out = make(ConfigValues, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	if in.Job != nil {
 		in, out := &in.Job, &out.Job
 		*out = new(JobStatus)
 		**out = **in
 	}
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]AppliedProfile, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastAppliedSpec != nil {
+		in, out := &in.LastAppliedSpec, &out.LastAppliedSpec
+		*out = new(StructureSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructureStatus.
@@ -17,21 +17,27 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"flag"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -42,6 +48,7 @@ import (
 	"t3kton.com/internal/controller"
 	webhookcontractorv1 "t3kton.com/internal/webhook/v1"
 	"t3kton.com/pkg/contractor"
+	"t3kton.com/pkg/tracing"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -57,6 +64,18 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
+// splitList splits a comma separated flag value, trimming the whitespace around each entry and dropping empty entries
+func splitList(value string) []string {
+	result := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
 // nolint:gocyclo
 func main() {
 	var metricsAddr string
@@ -67,9 +86,26 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var contractorHost string
+	var contractorEndpointCheckInterval time.Duration
 	var contractorProxy string
 	var contractorUsername string
 	var contractorPassword string
+	var contractorCredentialsDir string
+	var redactionKeyFile string
+	var contractorCredentialsSecret string
+	var contractorCredentialsInterval time.Duration
+	var contractorInsecureDefaultCredentials bool
+	var contractorCacheInterval time.Duration
+	var contractorJobPollInterval time.Duration
+	var contractorCallbackSecretFile string
+	var contractorCAFile, contractorCertPath, contractorCertName, contractorCertKey string
+	var contractorTLSMinVersion, contractorTLSServerName string
+	var contractorLimits contractor.LimitOptions
+	var webhookDegraded webhookcontractorv1.DegradedOptions
+	var contractorReadyCheck bool
+	var consumerAdminGroups string
+	var jobProgressMilestones string
+	var tracingOpts tracing.Options
 
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
@@ -89,10 +125,66 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
-	flag.StringVar(&contractorHost, "contractor-host", "http://contractor", "Contractor's hostname.")
+	flag.StringVar(&contractorHost, "contractor-host", "http://contractor",
+		"Contractor's hostname, a comma separated list of hostnames to fail over between, in order of preference.")
+	flag.DurationVar(&contractorEndpointCheckInterval, "contractor-endpoint-check-interval", time.Second*30,
+		"How often to check if a more preferred Contractor host is available again after failing over.")
 	flag.StringVar(&contractorProxy, "contractor-proxy", "", "Proxy to go through to get to the contractor host.")
-	flag.StringVar(&contractorUsername, "contractor-username", "k8s", "Contractor Username.")
-	flag.StringVar(&contractorPassword, "contractor-password", "k8s", "Contractor Password.")
+	flag.StringVar(&contractorUsername, "contractor-username", contractor.DefaultUsername, "Contractor Username.")
+	flag.StringVar(&contractorPassword, "contractor-password", contractor.DefaultPassword, "Contractor Password.")
+	flag.StringVar(&redactionKeyFile, "redaction-key-file", "",
+		"File containing the key used to hash sensitive config values in the status, required so the hashes are the same "+
+			"across restarts and replicas.")
+	flag.StringVar(&contractorCredentialsDir, "contractor-credentials-dir", "",
+		"The directory of a mounted basic-auth Secret with the Contractor username and password, "+
+			"overrides --contractor-username and --contractor-password.")
+	flag.StringVar(&contractorCredentialsSecret, "contractor-credentials-secret", "",
+		"The namespace/name of a basic-auth Secret with the Contractor username and password, the shipped RBAC only "+
+			"allows reading Secrets in the manager's namespace, "+
+			"overrides --contractor-username and --contractor-password.")
+	flag.DurationVar(&contractorCredentialsInterval, "contractor-credentials-reload-interval", time.Minute,
+		"How often to check the credentials Secret for changes.")
+	flag.BoolVar(&contractorInsecureDefaultCredentials, "contractor-insecure-default-credentials", false,
+		"If set, allow starting with the default Contractor credentials.")
+	flag.DurationVar(&contractorCacheInterval, "contractor-cache-interval", time.Second*30,
+		"How often the cached Contractor structure state is refreshed, 0 disables the cache.")
+	flag.DurationVar(&contractorJobPollInterval, "contractor-job-poll-interval", time.Second*5,
+		"How often Contractor is checked for structure job changes, 0 disables the poller and structures with jobs poll on their own.")
+	flag.StringVar(&contractorCallbackSecretFile, "contractor-callback-secret-file", "",
+		"A file with the HMAC secret Contractor notifications are signed with, enables the "+contractor.CallbackPath+
+			" endpoint on the webhook server.")
+	flag.StringVar(&contractorCAFile, "contractor-ca-file", "",
+		"A PEM bundle of the CAs to trust for Contractor's certificate, the system CAs are used if not set.")
+	flag.StringVar(&contractorCertPath, "contractor-cert-path", "",
+		"The directory that contains the client certificate to present to Contractor.")
+	flag.StringVar(&contractorCertName, "contractor-cert-name", "tls.crt", "The name of the Contractor client certificate file.")
+	flag.StringVar(&contractorCertKey, "contractor-cert-key", "tls.key", "The name of the Contractor client key file.")
+	flag.StringVar(&contractorTLSMinVersion, "contractor-tls-min-version", "1.2",
+		"The minimum TLS version for the connection to Contractor, 1.2 or 1.3.")
+	flag.StringVar(&contractorTLSServerName, "contractor-tls-server-name", "",
+		"Overrides the name Contractor's certificate is verified against.")
+	flag.Float64Var(&contractorLimits.QPS, "contractor-qps", 20, "Maximum requests per second to Contractor, 0 for no limit.")
+	flag.IntVar(&contractorLimits.Burst, "contractor-burst", 40, "Maximum burst of requests to Contractor.")
+	flag.IntVar(&contractorLimits.FailureThreshold, "contractor-breaker-failures", 5,
+		"Consecutive Contractor connection, timeout or 502/503/504 failures before calls are refused for --contractor-breaker-open-duration, 0 disables the circuit breaker.")
+	flag.DurationVar(&contractorLimits.OpenDuration, "contractor-breaker-open-duration", time.Second*30,
+		"How long calls to Contractor are refused once the circuit breaker opens.")
+	flag.BoolVar(&contractorReadyCheck, "contractor-readiness-check", true,
+		"If set, the operator is only Ready when it can reach and authenticate to Contractor, not used with "+
+			"--webhook-degraded-mode as that would take the webhooks out of service while Contractor is unavailable.")
+	flag.BoolVar(&webhookDegraded.Enabled, "webhook-degraded-mode", false,
+		"If set, the Structure webhook admits changes while Contractor is unavailable, changes that need Contractor are "+
+			"checked against the cached structure state and re-validated by the controller once Contractor is available.")
+	flag.DurationVar(&webhookDegraded.MaxCacheAge, "webhook-degraded-max-cache-age", time.Minute*5,
+		"How old the cached structure state can be and still be used by the degraded webhook, requires the cache.")
+	flag.StringVar(&consumerAdminGroups, "consumer-admin-groups", "system:masters",
+		"Comma separated list of groups who's members can change the consumerRef of a bound Structure.")
+	flag.StringVar(&jobProgressMilestones, "job-progress-milestones", "25,50,75",
+		"Comma separated list of job progress percentages to record a JobProgress event at.")
+	flag.StringVar(&tracingOpts.Endpoint, "otlp-endpoint", "",
+		"The host:port of an OTLP gRPC collector to send traces to, tracing is disabled if not set.")
+	flag.BoolVar(&tracingOpts.Insecure, "otlp-insecure", false, "If set, connect to the OTLP collector without TLS.")
+	flag.Float64Var(&tracingOpts.SampleRatio, "trace-sample-ratio", 1, "The fraction of new traces to sample.")
 
 	opts := zap.Options{
 		Development: true,
@@ -142,8 +234,87 @@ func main() {
 		})
 	}
 
+	if redactionKeyFile == "" {
+		setupLog.Error(nil, "--redaction-key-file is required")
+		os.Exit(1)
+	}
+	key, err := os.ReadFile(redactionKeyFile)
+	if err != nil {
+		setupLog.Error(err, "unable to read the redaction key")
+		os.Exit(1)
+	}
+	if len(bytes.TrimSpace(key)) == 0 {
+		setupLog.Error(nil, "the redaction key is empty", "redaction-key-file", redactionKeyFile)
+		os.Exit(1)
+	}
+	contractorv1.SetRedactionKey(bytes.TrimSpace(key))
+
+	restConfig := ctrl.GetConfigOrDie()
+
+	var credentialSource contractor.CredentialSource
+	if contractorCredentialsDir != "" {
+		credentialSource = contractor.FileCredentials{Dir: contractorCredentialsDir}
+	} else if contractorCredentialsSecret != "" {
+		namespace, name, found := strings.Cut(contractorCredentialsSecret, "/")
+		if !found {
+			setupLog.Error(nil, "--contractor-credentials-secret must be in the form namespace/name")
+			os.Exit(1)
+		}
+
+		reader, err := client.New(restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client for reading the credentials secret")
+			os.Exit(1)
+		}
+		credentialSource = contractor.SecretCredentials{Reader: reader, Name: types.NamespacedName{Namespace: namespace, Name: name}}
+	} else {
+		credentialSource = contractor.StaticCredentials{Username: contractorUsername, Password: contractorPassword}
+	}
+
 	ctx := context.TODO()
-	err := contractor.SetupFactory(ctx, contractorHost, contractorUsername, contractorPassword, contractorProxy)
+	contractorCredentials, err := credentialSource.Credentials(ctx)
+	if err != nil {
+		setupLog.Error(err, "unable to get contractor credentials")
+		os.Exit(1)
+	}
+
+	if contractorCredentials.IsDefault() && !contractorInsecureDefaultCredentials {
+		setupLog.Error(nil, "refusing to start with the default contractor credentials, "+
+			"set --contractor-insecure-default-credentials to allow them")
+		os.Exit(1)
+	}
+
+	contractorTLSOpts := contractor.TLSOptions{CAFile: contractorCAFile, ServerName: contractorTLSServerName}
+	contractorTLSOpts.MinVersion, err = contractor.ParseTLSVersion(contractorTLSMinVersion)
+	if err != nil {
+		setupLog.Error(err, "invalid contractor TLS minimum version")
+		os.Exit(1)
+	}
+	if len(contractorCertPath) > 0 {
+		setupLog.Info("Initializing contractor client certificate watcher using provided certificates",
+			"contractor-cert-path", contractorCertPath, "contractor-cert-name", contractorCertName, "contractor-cert-key", contractorCertKey)
+		contractorTLSOpts.CertFile = filepath.Join(contractorCertPath, contractorCertName)
+		contractorTLSOpts.KeyFile = filepath.Join(contractorCertPath, contractorCertKey)
+	}
+	contractorCertWatcher, err := contractor.ConfigureTLS(contractorTLSOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to configure contractor TLS")
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	if tracingOpts.Endpoint != "" {
+		setupLog.Info("Tracing enabled", "otlp-endpoint", tracingOpts.Endpoint)
+		contractor.EnableTracing()
+	}
+
+	contractor.ConfigureLimits(contractorLimits)
+
+	err = contractor.SetupFactory(ctx, splitList(contractorHost), contractorCredentials, contractorProxy)
 	if err != nil {
 		setupLog.Error(err, "unable to connect to contractor")
 		os.Exit(1)
@@ -153,6 +324,22 @@ func main() {
 		TLSOpts: webhookTLSOpts,
 	})
 
+	var callbackHandler *contractor.CallbackHandler
+	if contractorCallbackSecretFile != "" {
+		secret, err := os.ReadFile(contractorCallbackSecretFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read contractor callback secret")
+			os.Exit(1)
+		}
+		secret = bytes.TrimSpace(secret)
+		if len(secret) == 0 {
+			setupLog.Error(nil, "contractor callback secret is empty")
+			os.Exit(1)
+		}
+		callbackHandler = contractor.NewCallbackHandler(secret)
+		webhookServer.Register(contractor.CallbackPath, callbackHandler)
+	}
+
 	// Metrics endpoint is enabled in 'config/default/kustomization.yaml'. The Metrics options configure the server.
 	// More info:
 	// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.20.2/pkg/metrics/server
@@ -198,7 +385,7 @@ func main() {
 		})
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
@@ -222,17 +409,52 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.StructureReconciler{
+	milestones := []int{}
+	for _, milestone := range strings.Split(jobProgressMilestones, ",") {
+		if milestone == "" {
+			continue
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(milestone))
+		if err != nil {
+			setupLog.Error(err, "invalid job progress milestone", "milestone", milestone)
+			os.Exit(1)
+		}
+		milestones = append(milestones, value)
+	}
+
+	structureReconciler := &controller.StructureReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("structure-controller"),
-	}).SetupWithManager(mgr); err != nil {
+		// without the webhooks nothing else checks the spec
+		ValidateSpec:          os.Getenv("ENABLE_WEBHOOKS") == "false",
+		JobProgressMilestones: milestones,
+	}
+	if contractorJobPollInterval > 0 {
+		jobPoller := contractor.NewJobPoller(contractorJobPollInterval)
+		if err := mgr.Add(jobPoller); err != nil {
+			setupLog.Error(err, "unable to add contractor job poller to manager")
+			os.Exit(1)
+		}
+		structureReconciler.JobEvents = jobPoller.Events()
+	}
+	if callbackHandler != nil {
+		structureReconciler.NotifyEvents = callbackHandler.Events()
+	}
+	if err = structureReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Structure")
 		os.Exit(1)
 	}
+	if err = (&controller.StructureQuotaReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "StructureQuota")
+		os.Exit(1)
+	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err = webhookcontractorv1.SetupStructureWebhookWithManager(mgr); err != nil {
+		if err = webhookcontractorv1.SetupStructureWebhookWithManager(mgr, webhookDegraded, splitList(consumerAdminGroups)); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Structure")
 			os.Exit(1)
 		}
@@ -255,6 +477,40 @@ func main() {
 		}
 	}
 
+	if contractorCertWatcher != nil {
+		setupLog.Info("Adding contractor client certificate watcher to manager")
+		if err := mgr.Add(contractorCertWatcher); err != nil {
+			setupLog.Error(err, "unable to add contractor client certificate watcher to manager")
+			os.Exit(1)
+		}
+	}
+
+	if _, static := credentialSource.(contractor.StaticCredentials); !static {
+		setupLog.Info("Adding contractor credentials watcher to manager")
+		if err := mgr.Add(&contractor.CredentialsWatcher{Source: credentialSource, Interval: contractorCredentialsInterval}); err != nil {
+			setupLog.Error(err, "unable to add contractor credentials watcher to manager")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.Add(&contractor.EndpointMonitor{Interval: contractorEndpointCheckInterval}); err != nil {
+		setupLog.Error(err, "unable to add contractor endpoint monitor to manager")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(&contractor.TokenRefresher{Interval: time.Minute}); err != nil {
+		setupLog.Error(err, "unable to add contractor token refresher to manager")
+		os.Exit(1)
+	}
+
+	if contractorCacheInterval > 0 {
+		setupLog.Info("Adding contractor cache refresher to manager")
+		if err := mgr.Add(contractor.SetupCache(contractorCacheInterval)); err != nil {
+			setupLog.Error(err, "unable to add contractor cache refresher to manager")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -263,10 +519,23 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	// Ready only when contractor can be reached and authenticated to, contractor_degraded reports the same for alerting
+	if contractorReadyCheck && !webhookDegraded.Enabled {
+		if err := mgr.AddReadyzCheck("contractor", (&contractor.HealthChecker{}).Check); err != nil {
+			setupLog.Error(err, "unable to set up contractor ready check")
+			os.Exit(1)
+		}
+	} else if contractorReadyCheck {
+		setupLog.Info("not adding the contractor ready check, the webhooks stay in service in degraded mode")
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "problem flushing traces")
+	}
 }
@@ -0,0 +1,132 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	contractorv1 "t3kton.com/api/v1"
+)
+
+// DefaultJobProgressMilestones are the job progress percentages a JobProgress event is recorded at
+var DefaultJobProgressMilestones = []int{25, 50, 75}
+
+// eventDeduper remembers the last deduplicated event recorded for each structure, so an event that is repeated
+// without the structure changing in between is only recorded once
+type eventDeduper struct {
+	lock sync.Mutex
+	last map[types.NamespacedName]string
+}
+
+func newEventDeduper() *eventDeduper {
+	return &eventDeduper{last: map[types.NamespacedName]string{}}
+}
+
+// dedupedEvents is the last deduplicated event recorded for each structure by the StructureReconciler
+var dedupedEvents = newEventDeduper()
+
+// record records the event, unless it was the last event recorded for the structure and the structure has not been
+// changed since
+func (d *eventDeduper) record(recorder record.EventRecorder, structure *contractorv1.Structure, eventtype, reason, message string) {
+	name := types.NamespacedName{Namespace: structure.Namespace, Name: structure.Name}
+	key := structure.ResourceVersion + "/" + eventtype + "/" + reason + "/" + message
+
+	d.lock.Lock()
+	if d.last[name] == key {
+		d.lock.Unlock()
+		return
+	}
+	d.last[name] = key
+	d.lock.Unlock()
+
+	recorder.Event(structure, eventtype, reason, message)
+}
+
+// forget stops tracking a structure that has been deleted
+func (d *eventDeduper) forget(name types.NamespacedName) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.last, name)
+}
+
+// recordJobChanges records the events for the changes from the last seen state of the job to the current one
+func (r *StructureReconciler) recordJobChanges(structure *contractorv1.Structure, old *contractorv1.JobStatus, current *contractorv1.JobStatus) {
+	if old == nil {
+		old = &contractorv1.JobStatus{}
+	}
+
+	if current.CanStart == "true" && old.CanStart != "true" {
+		r.Recorder.Event(structure, "Normal", "JobStarted", "job '"+current.Script+"' started")
+	}
+
+	if current.State != old.State {
+		switch current.State {
+		case "paused":
+			r.Recorder.Event(structure, "Normal", "JobPaused", "job '"+current.Script+"' paused: "+current.Message)
+		case "error":
+			r.Recorder.Event(structure, "Warning", "JobFailed", "job '"+current.Script+"' failed: "+current.Message)
+		}
+	}
+
+	if milestone, ok := r.progressMilestone(old.Progress, current.Progress); ok {
+		r.Recorder.Event(structure, "Normal", "JobProgress", fmt.Sprintf("job '%s' is %d%% complete", current.Script, milestone))
+	}
+}
+
+// recordJobFinished records the event for a job that has gone away, failures are recorded when the job errors
+func (r *StructureReconciler) recordJobFinished(structure *contractorv1.Structure, job *contractorv1.JobStatus) {
+	if jobOutcome(job) != "succeeded" {
+		r.Recorder.Event(structure, "Normal", "JobFinished", "Job '"+job.Script+"' finished")
+		return
+	}
+
+	if age, ok := jobAge(job); ok {
+		r.Recorder.Event(structure, "Normal", "JobSucceeded", "job '"+job.Script+"' succeeded in "+age.Round(time.Second).String())
+		return
+	}
+	r.Recorder.Event(structure, "Normal", "JobSucceeded", "job '"+job.Script+"' succeeded")
+}
+
+// progressMilestone returns the highest milestone passed going from the old to the current progress
+func (r *StructureReconciler) progressMilestone(old string, current string) (int, bool) {
+	milestones := r.JobProgressMilestones
+	if milestones == nil {
+		milestones = DefaultJobProgressMilestones
+	}
+
+	from, _ := strconv.ParseFloat(old, 64) // a new job, or no progress, is 0
+	to, err := strconv.ParseFloat(current, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	passed, ok := 0, false
+	for _, milestone := range milestones {
+		if from < float64(milestone) && to >= float64(milestone) && milestone > passed {
+			passed, ok = milestone, true
+		}
+	}
+
+	return passed, ok
+}
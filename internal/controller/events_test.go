@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	contractorv1 "t3kton.com/api/v1"
+)
+
+var _ = Describe("Events", func() {
+	var recorder *record.FakeRecorder
+	var reconciler *StructureReconciler
+	var structure *contractorv1.Structure
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &StructureReconciler{Recorder: recorder}
+		structure = &contractorv1.Structure{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "events", ResourceVersion: "1"}}
+	})
+
+	It("Deduplicates repeated events", func() {
+		deduper := newEventDeduper()
+
+		deduper.record(recorder, structure, "Normal", "ReconcileComplete", "reconcile complete")
+		deduper.record(recorder, structure, "Normal", "ReconcileComplete", "reconcile complete")
+		Expect(recorder.Events).To(Receive(Equal("Normal ReconcileComplete reconcile complete")))
+		Expect(recorder.Events).NotTo(Receive())
+
+		structure.ResourceVersion = "2"
+		deduper.record(recorder, structure, "Normal", "ReconcileComplete", "reconcile complete")
+		Expect(recorder.Events).To(Receive(Equal("Normal ReconcileComplete reconcile complete")))
+
+		deduper.forget(types.NamespacedName{Namespace: "default", Name: "events"})
+		deduper.record(recorder, structure, "Normal", "ReconcileComplete", "reconcile complete")
+		Expect(recorder.Events).To(Receive(Equal("Normal ReconcileComplete reconcile complete")))
+	})
+
+	It("Records job changes", func() {
+		job := &contractorv1.JobStatus{Script: "create", State: "queued", CanStart: "false", Progress: "0"}
+		reconciler.recordJobChanges(structure, nil, job)
+		Expect(recorder.Events).NotTo(Receive())
+
+		started := job.DeepCopy()
+		started.CanStart = "true"
+		started.Progress = "10.0"
+		reconciler.recordJobChanges(structure, job, started)
+		Expect(recorder.Events).To(Receive(Equal("Normal JobStarted job 'create' started")))
+		Expect(recorder.Events).NotTo(Receive())
+
+		// only the highest milestone passed is recorded
+		progressed := started.DeepCopy()
+		progressed.Progress = "60.5"
+		reconciler.recordJobChanges(structure, started, progressed)
+		Expect(recorder.Events).To(Receive(Equal("Normal JobProgress job 'create' is 50% complete")))
+		Expect(recorder.Events).NotTo(Receive())
+
+		paused := progressed.DeepCopy()
+		paused.State = "paused"
+		paused.Message = "waiting for approval"
+		reconciler.recordJobChanges(structure, progressed, paused)
+		Expect(recorder.Events).To(Receive(Equal("Normal JobPaused job 'create' paused: waiting for approval")))
+
+		failed := paused.DeepCopy()
+		failed.State = "error"
+		failed.Message = "power on failed"
+		reconciler.recordJobChanges(structure, paused, failed)
+		Expect(recorder.Events).To(Receive(Equal("Warning JobFailed job 'create' failed: power on failed")))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("Uses configured milestones", func() {
+		reconciler.JobProgressMilestones = []int{}
+		_, ok := reconciler.progressMilestone("0", "100.0")
+		Expect(ok).To(BeFalse())
+
+		reconciler.JobProgressMilestones = []int{90}
+		milestone, ok := reconciler.progressMilestone("80", "100.0")
+		Expect(ok).To(BeTrue())
+		Expect(milestone).To(Equal(90))
+	})
+
+	It("Records finished jobs", func() {
+		created := time.Now().Add(-time.Minute * 5).Format(time.RFC3339)
+
+		reconciler.recordJobFinished(structure, &contractorv1.JobStatus{Script: "create", State: "done", Created: created})
+		Expect(recorder.Events).To(Receive(MatchRegexp(`^Normal JobSucceeded job 'create' succeeded in 5m[0-9]+s$`)))
+
+		reconciler.recordJobFinished(structure, &contractorv1.JobStatus{Script: "destroy", State: "aborted", Created: created})
+		Expect(recorder.Events).To(Receive(Equal("Normal JobFinished Job 'destroy' finished")))
+	})
+})
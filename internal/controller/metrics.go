@@ -0,0 +1,217 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	contractorv1 "t3kton.com/api/v1"
+)
+
+var (
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "structure_job_duration_seconds",
+		Help:    "Time from a structure job being created to it going away, by script and outcome",
+		Buckets: prometheus.ExponentialBuckets(30, 2, 10), // 30 seconds to a little over 4 hours
+	}, []string{"script", "outcome"})
+
+	convergenceDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "structure_convergence_seconds",
+		Help:    "Time from a structure's spec changing to contractor matching it",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 15), // 1 second to a little over 4 hours
+	})
+
+	structuresDesc = prometheus.NewDesc("structures",
+		"Number of structures by current state, blueprint and foundation type",
+		[]string{"state", "blueprint", "foundation_type"}, nil)
+	jobsActiveDesc = prometheus.NewDesc("structure_jobs_active",
+		"Number of structures with a job by job script",
+		[]string{"script"}, nil)
+	jobProgressDesc = prometheus.NewDesc("structure_job_progress",
+		"Progress, in percent, of the structure's job",
+		[]string{"namespace", "name", "script"}, nil)
+	driftedDesc = prometheus.NewDesc("structures_drifted",
+		"Number of structures who's current state or blueprint does not match the spec",
+		[]string{"field"}, nil)
+)
+
+// convergence tracks the structures reconciled by the StructureReconciler
+var convergence = newConvergenceTracker()
+
+func init() {
+	metrics.Registry.MustRegister(jobDuration, convergenceDuration)
+}
+
+// jobOutcome is the outcome of a job that has gone away, from the last state it was seen in
+func jobOutcome(job *contractorv1.JobStatus) string {
+	switch job.State {
+	case "error":
+		return "failed"
+	case "aborted":
+		return "aborted"
+	}
+
+	return "succeeded"
+}
+
+// jobAge is how long ago the job was created
+func jobAge(job *contractorv1.JobStatus) (time.Duration, bool) {
+	created, err := time.Parse(time.RFC3339, job.Created)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Since(created), true
+}
+
+// observeJobFinished records the duration of a job that has gone away
+func observeJobFinished(job *contractorv1.JobStatus) {
+	age, ok := jobAge(job)
+	if !ok {
+		return
+	}
+
+	jobDuration.WithLabelValues(job.Script, jobOutcome(job)).Observe(age.Seconds())
+}
+
+// convergenceTracker keeps track of when the spec of each structure changed, so the time it takes for contractor to
+// match can be observed
+type convergenceTracker struct {
+	lock sync.Mutex
+	// seen is the last generation reconciled
+	seen map[types.NamespacedName]int64
+	// changed is when the spec changed, for structures that have not converged yet
+	changed map[types.NamespacedName]time.Time
+}
+
+func newConvergenceTracker() *convergenceTracker {
+	return &convergenceTracker{seen: map[types.NamespacedName]int64{}, changed: map[types.NamespacedName]time.Time{}}
+}
+
+// observe notes the generation of the structure being reconciled, when the operator restarts the time of changes
+// made before the restart is not known, with the exception of structures that have never been reconciled
+func (t *convergenceTracker) observe(structure *contractorv1.Structure) {
+	name := types.NamespacedName{Namespace: structure.Namespace, Name: structure.Name}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	last, ok := t.seen[name]
+	t.seen[name] = structure.Generation
+	if ok && last == structure.Generation {
+		return
+	}
+
+	if ok {
+		t.changed[name] = time.Now()
+	} else if structure.Status.State == "" {
+		t.changed[name] = structure.CreationTimestamp.Time
+	}
+}
+
+// converged records the time it took for the structure to converge, if it is being tracked
+func (t *convergenceTracker) converged(structure *contractorv1.Structure) {
+	name := types.NamespacedName{Namespace: structure.Namespace, Name: structure.Name}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	changed, ok := t.changed[name]
+	if !ok {
+		return
+	}
+	delete(t.changed, name)
+
+	convergenceDuration.Observe(time.Since(changed).Seconds())
+}
+
+// forget stops tracking a structure that has been deleted
+func (t *convergenceTracker) forget(name types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.seen, name)
+	delete(t.changed, name)
+}
+
+// fleetCollector reports the state of all the structures from the manager's cache when scraped
+type fleetCollector struct {
+	reader client.Reader
+}
+
+// Describe implements prometheus.Collector
+func (c *fleetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- structuresDesc
+	ch <- jobsActiveDesc
+	ch <- jobProgressDesc
+	ch <- driftedDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *fleetCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+
+	var structures contractorv1.StructureList
+	if err := c.reader.List(ctx, &structures); err != nil {
+		log.FromContext(ctx).Error(err, "listing structures for metrics failed")
+		return
+	}
+
+	type structureKey struct{ state, blueprint, foundationType string }
+	counts := map[structureKey]int{}
+	jobs := map[string]int{}
+	drifted := map[string]int{"state": 0, "blueprint": 0}
+
+	for _, structure := range structures.Items {
+		counts[structureKey{structure.Status.State, structure.Status.BluePrint, structure.Status.FoundationType}]++
+
+		if structure.Status.State != "" && structure.Status.State != structure.Spec.State {
+			drifted["state"]++
+		}
+		if structure.Status.BluePrint != "" && structure.Status.BluePrint != structure.Spec.BluePrint {
+			drifted["blueprint"]++
+		}
+
+		job := structure.Status.Job
+		if job == nil {
+			continue
+		}
+		jobs[job.Script]++
+		if progress, err := strconv.ParseFloat(job.Progress, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(jobProgressDesc, prometheus.GaugeValue, progress, structure.Namespace, structure.Name, job.Script)
+		}
+	}
+
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(structuresDesc, prometheus.GaugeValue, float64(count), key.state, key.blueprint, key.foundationType)
+	}
+	for script, count := range jobs {
+		ch <- prometheus.MustNewConstMetric(jobsActiveDesc, prometheus.GaugeValue, float64(count), script)
+	}
+	for field, count := range drifted {
+		ch <- prometheus.MustNewConstMetric(driftedDesc, prometheus.GaugeValue, float64(count), field)
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	contractorv1 "t3kton.com/api/v1"
+)
+
+var _ = Describe("Metrics", func() {
+	It("Observes job durations by outcome", func() {
+		created := time.Now().Add(-time.Minute * 10).Format(time.RFC3339)
+
+		before := testutil.CollectAndCount(jobDuration)
+		observeJobFinished(&contractorv1.JobStatus{Script: "metricsjob", State: "done", Created: created})
+		observeJobFinished(&contractorv1.JobStatus{Script: "metricsjob", State: "error", Created: created})
+		observeJobFinished(&contractorv1.JobStatus{Script: "metricsjob", State: "done", Created: "not a time"})
+		Expect(testutil.CollectAndCount(jobDuration)).To(Equal(before + 2))
+
+		Expect(jobOutcome(&contractorv1.JobStatus{State: "aborted"})).To(Equal("aborted"))
+	})
+
+	It("Tracks convergence", func() {
+		tracker := newConvergenceTracker()
+		name := types.NamespacedName{Namespace: "default", Name: "metrics-convergence"}
+		structure := &contractorv1.Structure{ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name, Generation: 3}}
+		structure.Status.State = "planned"
+
+		// already synced before we started watching it, the change time is not known
+		tracker.observe(structure)
+		Expect(tracker.changed).NotTo(HaveKey(name))
+
+		tracker.observe(structure)
+		Expect(tracker.changed).NotTo(HaveKey(name))
+
+		structure.Generation = 4
+		tracker.observe(structure)
+		Expect(tracker.changed).To(HaveKey(name))
+
+		tracker.converged(structure)
+		Expect(tracker.changed).NotTo(HaveKey(name))
+
+		tracker.forget(name)
+		Expect(tracker.seen).NotTo(HaveKey(name))
+
+		// never synced, so it is new
+		structure.Status.State = ""
+		tracker.observe(structure)
+		Expect(tracker.changed).To(HaveKey(name))
+	})
+
+	It("Collects the fleet", func() {
+		Expect(contractorv1.AddToScheme(scheme.Scheme)).To(Succeed())
+		reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			&contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "built"},
+				Spec:       contractorv1.StructureSpec{ID: 1, State: "built", BluePrint: "bp"},
+				Status:     contractorv1.StructureStatus{State: "built", BluePrint: "bp", FoundationType: "VM"},
+			},
+			&contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "building"},
+				Spec:       contractorv1.StructureSpec{ID: 2, State: "built", BluePrint: "bp"},
+				Status: contractorv1.StructureStatus{State: "planned", BluePrint: "bp", FoundationType: "VM",
+					Job: &contractorv1.JobStatus{Script: "create", Progress: "42.5"}},
+			},
+		).Build()
+
+		expected := `
+# HELP structure_job_progress Progress, in percent, of the structure's job
+# TYPE structure_job_progress gauge
+structure_job_progress{name="building",namespace="default",script="create"} 42.5
+# HELP structure_jobs_active Number of structures with a job by job script
+# TYPE structure_jobs_active gauge
+structure_jobs_active{script="create"} 1
+# HELP structures Number of structures by current state, blueprint and foundation type
+# TYPE structures gauge
+structures{blueprint="bp",foundation_type="VM",state="built"} 1
+structures{blueprint="bp",foundation_type="VM",state="planned"} 1
+# HELP structures_drifted Number of structures who's current state or blueprint does not match the spec
+# TYPE structures_drifted gauge
+structures_drifted{field="blueprint"} 0
+structures_drifted{field="state"} 1
+`
+		Expect(testutil.CollectAndCompare(&fleetCollector{reader: reader}, strings.NewReader(expected))).To(Succeed())
+	})
+})
@@ -20,26 +20,47 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"t3kton.com/pkg/contractor"
+	"t3kton.com/pkg/tracing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
 	cclient "github.com/t3kton/contractor_goclient"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	contractorv1 "t3kton.com/api/v1"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	profilesIndexField = ".spec.profiles"
+	idIndexField       = ".spec.id"
+	// jobResyncInterval is how often a Structure with a job is checked when the job poller is watching for job changes
+	jobResyncInterval = time.Minute * 5
 )
 
 // StructureReconciler reconciles a Structure object
@@ -47,24 +68,60 @@ type StructureReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// JobEvents, if set, sends the ID of Structures who's contractor job has changed, see contractor.JobPoller
+	JobEvents <-chan event.TypedGenericEvent[int]
+	// NotifyEvents, if set, sends the ID of Structures contractor has notified us have changed, see contractor.CallbackHandler
+	NotifyEvents <-chan event.TypedGenericEvent[int]
+	// ValidateSpec validates spec changes before acting on them, for when the webhooks are disabled
+	ValidateSpec bool
+	// JobProgressMilestones are the job progress percentages to record a JobProgress event at, if nil
+	// DefaultJobProgressMilestones are used
+	JobProgressMilestones []int
 }
 
 // +kubebuilder:rbac:groups=contractor.t3kton.com,resources=structures,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=contractor.t3kton.com,resources=structures/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=contractor.t3kton.com,resources=structures/finalizers,verbs=update
+// +kubebuilder:rbac:groups=contractor.t3kton.com,resources=configprofiles,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.20.2/pkg/reconcile
 func (r *StructureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, "Structure.Reconcile", trace.WithAttributes(
+		attribute.String("namespace", req.Namespace),
+		attribute.String("name", req.Name),
+	))
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+
+	return result, err
+}
+
+func (r *StructureReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconciling Structure", "request", req)
 
 	var structure contractorv1.Structure
 
 	err := r.Get(ctx, req.NamespacedName, &structure)
+	if apierrors.IsNotFound(err) {
+		convergence.forget(req.NamespacedName)
+		dedupedEvents.forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
 	if err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return ctrl.Result{}, err
+	}
+	convergence.observe(&structure)
+
+	if !structure.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, &structure)
+	}
+
+	// consent is for a single change, once the webhook has admitted it it must not be left to allow the next one
+	if _, ok := structure.Annotations[contractorv1.ConsumerConsentAnnotation]; ok {
+		return r.clearConsent(ctx, &structure)
 	}
 
 	// This should never happen, but just incase
@@ -79,23 +136,37 @@ func (r *StructureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, fmt.Errorf("structure is not fully defined")
 	}
 
-	client := contractor.GetClient(ctx)
+	configValues, appliedProfiles, err := r.desiredConfigValues(ctx, &structure)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-	logger.Info("Getting Structure", "id", structure.Spec.ID)
-	t3kton_structure, err := client.BuildingStructureGet(ctx, structure.Spec.ID)
+	client, err := contractor.GetClient(ctx)
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "get structure faild")
+		return ctrl.Result{}, r.setContractorUnavailable(ctx, &structure, err)
 	}
 
-	status := contractorv1.StructureStatus{}
-	err = updateStatus(ctx, logger, client, t3kton_structure, &status)
+	// the webhook admitted this spec while contractor was unavailable, check it before doing anything with it
+	if _, ok := structure.Annotations[contractorv1.UnvalidatedAnnotation]; ok {
+		return r.revalidate(ctx, &structure, client)
+	}
+
+	state, err := contractor.GetStructureState(ctx, structure.Spec.ID)
+	if errors.Is(err, contractor.ErrCircuitOpen) {
+		return ctrl.Result{}, r.setContractorUnavailable(ctx, &structure, err)
+	}
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "update status faild")
+		return ctrl.Result{}, err
 	}
+	t3kton_structure := state.Structure
+
+	status := contractorv1.StructureStatus{}
+	updateStatus(state, &status, structure.Spec.SensitiveKeys)
 
 	// See if an existing job has finished
 	if structure.Status.Job != nil && status.Job == nil {
-		r.Recorder.Event(&structure, "Normal", "JobFinished", "Job '"+structure.Status.Job.Script+"' finished")
+		r.recordJobFinished(&structure, structure.Status.Job)
+		observeJobFinished(structure.Status.Job)
 
 		structure.Status.Job = nil
 		err = r.Status().Update(ctx, &structure)
@@ -135,14 +206,20 @@ func (r *StructureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		changed = append(changed, "ConfigValues")
 		dirty = true
 	}
+	if !cmp.Equal(status.EffectiveConfig, structure.Status.EffectiveConfig) {
+		structure.Status.EffectiveConfig = status.EffectiveConfig.DeepCopy()
+		changed = append(changed, "EffectiveConfig")
+		dirty = true
+	}
 	// This one is just so we can watch the job come and go
 	// for somereason cmp.Equal(nil, nil) is false here
 	if !cmp.Equal(structure.Status.Job, status.Job) && status.Job != nil {
+		r.recordJobChanges(&structure, structure.Status.Job, status.Job)
 		structure.Status.Job = status.Job.DeepCopy()
 		changed = append(changed, "Job")
 		dirty = true
 	}
-	// These two can't be changed in k8s, we are replicating them here for information purposes
+	// These can't be changed in k8s, we are replicating them here for information purposes
 	if structure.Status.Foundation != status.Foundation {
 		structure.Status.Foundation = status.Foundation
 		changed = append(changed, "Foundation")
@@ -153,6 +230,35 @@ func (r *StructureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		changed = append(changed, "FoundationBluePrint")
 		dirty = true
 	}
+	if structure.Status.FoundationType != status.FoundationType {
+		structure.Status.FoundationType = status.FoundationType
+		changed = append(changed, "FoundationType")
+		dirty = true
+	}
+	// only recorded once contractor has been unavailable, so a healthy structure does not carry the condition
+	if meta.FindStatusCondition(structure.Status.Conditions, contractorv1.ConditionContractorAvailable) != nil {
+		if meta.SetStatusCondition(&structure.Status.Conditions, metav1.Condition{
+			Type:               contractorv1.ConditionContractorAvailable,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Authenticated",
+			ObservedGeneration: structure.Generation,
+		}) {
+			changed = append(changed, "Conditions")
+			dirty = true
+		}
+	}
+
+	// the spec has been fixed and validated by the webhook
+	if !r.ValidateSpec && meta.IsStatusConditionTrue(structure.Status.Conditions, contractorv1.ConditionInvalidSpec) {
+		meta.SetStatusCondition(&structure.Status.Conditions, metav1.Condition{
+			Type:               contractorv1.ConditionInvalidSpec,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Validated",
+			ObservedGeneration: structure.Generation,
+		})
+		changed = append(changed, "Conditions")
+		dirty = true
+	}
 
 	if dirty {
 		logger.Info("Status Change Detected", "changed", changed)
@@ -168,60 +274,90 @@ func (r *StructureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// the status matches contractor, decide what to do the same way the dry run plan does
+	action := structure.NextAction(configValues, structure.Status)
+
 	// if there is a job, requeue and wait for the job to finish before we do anything else
-	if structure.Status.Job != nil {
+	if action == contractorv1.ActionWaitForJob {
+		if r.JobEvents != nil {
+			return ctrl.Result{RequeueAfter: jobResyncInterval}, nil // the job poller will let us know when the job changes
+		}
 		return ctrl.Result{RequeueAfter: time.Second * 30}, nil // TODO: should this be a regular requeue?
 	}
 
+	// the webhooks are disabled, make sure the spec is valid before acting on it, the status is kept up to date either way
+	if r.ValidateSpec {
+		lastApplied := structure.Spec.DeepCopy()
+		lastApplied.ConfigValues = lastApplied.ConfigValues.Redact(lastApplied.SensitiveKeys)
+		if structure.Status.LastAppliedSpec == nil || !cmp.Equal(*lastApplied, *structure.Status.LastAppliedSpec) {
+			return r.validateSpec(ctx, &structure, client, lastApplied)
+		}
+	}
+
 	// Check Config Values, if need changing, change them then requeue, no delay
 	// This is the only thing in the spec that does not require a job
-	if !cmp.Equal(structure.Spec.ConfigValues, status.ConfigValues) {
+	if action == contractorv1.ActionPushConfig {
 		// We only want to update the config values, make an empty copy with only config values so only thoes get updated
 		tmp_structure := client.BuildingStructureNewWithID(*t3kton_structure.ID)
-		tmp_ConfigValues := structure.Spec.ConfigValues.ToContractor()
+		tmp_ConfigValues := configValues.ToContractor()
 		tmp_structure.ConfigValues = &tmp_ConfigValues
 		_, err := tmp_structure.Update(ctx)
+		contractor.InvalidateStructure(structure.Spec.ID)
 		if err != nil {
 			return ctrl.Result{Requeue: false}, errors.Wrap(err, "update config values on contractor faild") // TODO: Check to see if it is something that could be retried
 		}
 		logger.Info("ConfigValues updated")
+		r.Recorder.Event(&structure, "Normal", "ConfigValuesPushed", "config values pushed to contractor: "+strings.Join(contractorv1.ConfigChanges(configValues.Redact(structure.Spec.SensitiveKeys), status.ConfigValues), ", "))
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// Wait for the job to be cleared up and the state to be set
-	if (structure.Status.State == structure.Spec.State) && (structure.Status.BluePrint == structure.Spec.BluePrint) {
-		r.Recorder.Event(&structure, "Normal", "ReconcileComplete", "reconcile complete")
+	// The config values are in sync, record which profile revisions they came from
+	if !slices.Equal(structure.Status.Profiles, appliedProfiles) {
+		structure.Status.Profiles = appliedProfiles
+		err = r.Status().Update(ctx, &structure)
+		if apierrors.IsConflict(err) {
+			logger.Info("Structure Changed on us, will try again")
+			return ctrl.Result{Requeue: true}, nil
+		}
+
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "update status faild")
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	var jobName string
+	switch action {
+	case contractorv1.ActionNone:
+		dedupedEvents.record(r.Recorder, &structure, "Normal", "ReconcileComplete", "reconcile complete")
+		convergence.converged(&structure)
 		logger.Info("Reconciled Structure")
 		return ctrl.Result{}, nil
-	}
 
-	if structure.Status.BluePrint != structure.Spec.BluePrint {
-		// If we are already in planned state, we can update the blueprint, no destroy job needed
-		if structure.Status.State == "planned" {
-			tmp_structure := client.BuildingStructureNewWithID(*t3kton_structure.ID)
-			tmp_blueprint := "/api/v1/BluePrint/StructureBluePrint:" + structure.Spec.BluePrint + ":"
-			tmp_structure.Blueprint = &tmp_blueprint
-			_, err := tmp_structure.Update(ctx)
-			if err != nil {
-				return ctrl.Result{Requeue: false}, errors.Wrap(err, "update blueprint on contractor faild") // TODO: Check to see if it is something that could be retried
-			}
-			logger.Info("BluePrint updated")
-			return ctrl.Result{Requeue: true}, nil
+	case contractorv1.ActionUpdateBluePrint:
+		tmp_structure := client.BuildingStructureNewWithID(*t3kton_structure.ID)
+		tmp_blueprint := "/api/v1/BluePrint/StructureBluePrint:" + structure.Spec.BluePrint + ":"
+		tmp_structure.Blueprint = &tmp_blueprint
+		_, err := tmp_structure.Update(ctx)
+		contractor.InvalidateStructure(structure.Spec.ID)
+		if err != nil {
+			return ctrl.Result{Requeue: false}, errors.Wrap(err, "update blueprint on contractor faild") // TODO: Check to see if it is something that could be retried
 		}
-		// fallthrough to the destroy job creation
-	}
+		logger.Info("BluePrint updated")
+		r.Recorder.Event(&structure, "Normal", "BluePrintChanged", "blueprint changed from '"+structure.Status.BluePrint+"' to '"+structure.Spec.BluePrint+"'")
+		return ctrl.Result{Requeue: true}, nil
 
 	// Guess we need to make a job then
-	var jobName string
-	if structure.Spec.State == "built" {
+	case contractorv1.ActionCreateJob:
 		jobName = "create"
-	} else if structure.Spec.State == "planned" {
+	case contractorv1.ActionDestroyJob:
 		jobName = "destroy"
-	} else {
+	default:
 		return ctrl.Result{}, fmt.Errorf("invalid target state")
 	}
 
 	jobID, err := r.startJob(ctx, logger, client, structure.Spec.ID, jobName)
+	contractor.InvalidateStructure(structure.Spec.ID)
 	if err != nil {
 		return ctrl.Result{Requeue: false}, errors.Wrap(err, "job create faild") // TODO: Check to see if it is something that could be retried
 	}
@@ -231,11 +367,97 @@ func (r *StructureReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *StructureReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &contractorv1.Structure{}, profilesIndexField, func(obj client.Object) []string {
+		return obj.(*contractorv1.Structure).Spec.Profiles
+	})
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &contractorv1.Structure{}, idIndexField, func(obj client.Object) []string {
+		return []string{strconv.Itoa(obj.(*contractorv1.Structure).Spec.ID)}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := metrics.Registry.Register(&fleetCollector{reader: mgr.GetClient()}); err != nil {
+		return err
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 1}). // TODO: rate limiter, make sure it isn't reconciling the same structure multiple times at the same time
 		For(&contractorv1.Structure{}).
-		Named("structure").
-		Complete(r)
+		Watches(&contractorv1.ConfigProfile{}, handler.EnqueueRequestsFromMapFunc(r.structuresForProfile))
+
+	if r.JobEvents != nil {
+		builder = builder.WatchesRawSource(source.Channel(r.JobEvents, handler.TypedEnqueueRequestsFromMapFunc(r.structuresForID)))
+	}
+	if r.NotifyEvents != nil {
+		builder = builder.WatchesRawSource(source.Channel(r.NotifyEvents, handler.TypedEnqueueRequestsFromMapFunc(r.structuresForID)))
+	}
+
+	return builder.Named("structure").Complete(r)
+}
+
+// desiredConfigValues layers the Structure's profiles under it's ConfigValues, returning the resulting values and
+// the profile revisions used
+func (r *StructureReconciler) desiredConfigValues(ctx context.Context, structure *contractorv1.Structure) (contractorv1.ConfigValues, []contractorv1.AppliedProfile, error) {
+	if len(structure.Spec.Profiles) == 0 {
+		return structure.Spec.ConfigValues, nil, nil
+	}
+
+	layers := make([]contractorv1.ConfigValues, 0, len(structure.Spec.Profiles)+1)
+	applied := make([]contractorv1.AppliedProfile, 0, len(structure.Spec.Profiles))
+	for _, name := range structure.Spec.Profiles {
+		var profile contractorv1.ConfigProfile
+		err := r.Get(ctx, types.NamespacedName{Namespace: structure.Namespace, Name: name}, &profile)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "get config profile '%s' faild", name)
+		}
+		// there is no webhook for ConfigProfiles, so they are checked before they are used
+		if err := profile.Validate(); err != nil {
+			r.Recorder.Event(structure, "Warning", "InvalidProfile", "config profile '"+name+"' is invalid: "+err.Error())
+			return nil, nil, errors.Wrapf(err, "config profile '%s' is invalid", name)
+		}
+		layers = append(layers, profile.Spec.ConfigValues)
+		applied = append(applied, contractorv1.AppliedProfile{Name: name, Generation: profile.Generation})
+	}
+	layers = append(layers, structure.Spec.ConfigValues)
+
+	return contractorv1.MergeConfigValues(layers...), applied, nil
+}
+
+// structuresForProfile maps a ConfigProfile to the Structures that use it
+func (r *StructureReconciler) structuresForProfile(ctx context.Context, obj client.Object) []reconcile.Request {
+	var structures contractorv1.StructureList
+	err := r.List(ctx, &structures, client.InNamespace(obj.GetNamespace()), client.MatchingFields{profilesIndexField: obj.GetName()})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "listing structures for config profile failed", "profile", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, len(structures.Items))
+	for i, item := range structures.Items {
+		requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}}
+	}
+	return requests
+}
+
+// structuresForID maps a contractor structure ID from the job poller or a callback to the Structures for it
+func (r *StructureReconciler) structuresForID(ctx context.Context, id int) []reconcile.Request {
+	var structures contractorv1.StructureList
+	err := r.List(ctx, &structures, client.MatchingFields{idIndexField: strconv.Itoa(id)})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "listing structures for contractor structure failed", "structure", id)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, len(structures.Items))
+	for i, item := range structures.Items {
+		requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}}
+	}
+	return requests
 }
 
 // func (r *StructureReconciler) ownObject(ctx context.Context, cr *contractorv1.Structure, obj client.Object) error {
@@ -267,50 +489,193 @@ func (r *StructureReconciler) startJob(ctx context.Context, logger logr.Logger,
 	return jobID, nil
 }
 
-func updateStatus(ctx context.Context, logger logr.Logger, client *cclient.Contractor, structure *cclient.BuildingStructure, status *contractorv1.StructureStatus) error {
+// revalidate validates a spec the webhook admitted while contractor was unavailable.  If it is invalid the
+// InvalidSpec condition is set and nothing is done until the spec is changed, otherwise the unvalidated annotation
+// is removed and the structure is reconciled as usual
+func (r *StructureReconciler) revalidate(ctx context.Context, structure *contractorv1.Structure, client *cclient.Contractor) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
 
-	logger.Info("Getting Foundation", "id", *structure.Foundation)
-	foundation, err := client.BuildingFoundationGetURI(ctx, *structure.Foundation)
+	if invalid := utilerrors.NewAggregate(structure.ValidateStructure(ctx, client)); invalid != nil {
+		return r.setInvalidSpec(ctx, structure, invalid)
+	}
+
+	delete(structure.Annotations, contractorv1.UnvalidatedAnnotation)
+	err := r.Update(ctx, structure)
+	if apierrors.IsConflict(err) {
+		logger.Info("Structure Changed on us, will try again")
+		return ctrl.Result{Requeue: true}, nil
+	}
 	if err != nil {
-		return err
+		return ctrl.Result{}, errors.Wrap(err, "removing unvalidated annotation faild")
 	}
 
-	updateStructureStatus(structure, status)
+	logger.Info("Structure spec validated")
+	return ctrl.Result{Requeue: true}, nil
+}
 
-	updateFoundationStatus(foundation, status)
+// clearConsent removes the consumer consent annotation after the change it was given for has been admitted
+// finalize records the ForceDeleted event for a structure deleted with the force-delete annotation, and removes the
+// finalizer the webhook added so it can be deleted.  Nothing is done on contractor.
+func (r *StructureReconciler) finalize(ctx context.Context, structure *contractorv1.Structure) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
 
-	logger.Info("Getting Job", "structure", structure.ID)
-	jobURI, err := structure.CallGetJob(ctx)
+	if !controllerutil.ContainsFinalizer(structure, contractorv1.ForceDeleteFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	controllerutil.RemoveFinalizer(structure, contractorv1.ForceDeleteFinalizer)
+	err := r.Update(ctx, structure)
+	if apierrors.IsConflict(err) {
+		logger.Info("Structure Changed on us, will try again")
+		return ctrl.Result{Requeue: true}, nil
+	}
 	if err != nil {
-		return err
+		return ctrl.Result{}, errors.Wrap(err, "removing force delete finalizer faild")
 	}
 
-	if jobURI == "" {
-		status.Job = nil
-		return nil
+	if reason, force := structure.ForceDeleteReason(); force {
+		logger.Info("Structure force deleted", "by", structure.Annotations[contractorv1.ForceDeleteByAnnotation], "reason", reason)
+		r.Recorder.Eventf(structure, "Warning", "ForceDeleted", "force deleted, requested by '%s': %s", structure.Annotations[contractorv1.ForceDeleteByAnnotation], reason)
 	}
 
-	job, err := client.ForemanStructureJobGetURI(ctx, jobURI)
+	return ctrl.Result{}, nil
+}
+
+func (r *StructureReconciler) clearConsent(ctx context.Context, structure *contractorv1.Structure) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	delete(structure.Annotations, contractorv1.ConsumerConsentAnnotation)
+	err := r.Update(ctx, structure)
+	if apierrors.IsConflict(err) {
+		logger.Info("Structure Changed on us, will try again")
+		return ctrl.Result{Requeue: true}, nil
+	}
 	if err != nil {
-		return err
+		return ctrl.Result{}, errors.Wrap(err, "removing consumer consent annotation faild")
 	}
 
-	updateJobStatus(job, status)
+	logger.Info("Consumer consent used")
+	return ctrl.Result{Requeue: true}, nil
+}
 
-	return nil
+// validateSpec validates a changed spec against the spec the controller last validated, the same as the webhook
+// does, for when the webhooks are disabled.  If it is valid, lastApplied is recorded in the status.
+func (r *StructureReconciler) validateSpec(ctx context.Context, structure *contractorv1.Structure, client *cclient.Contractor, lastApplied *contractorv1.StructureSpec) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var errs []error
+	if structure.Status.LastAppliedSpec == nil {
+		errs = structure.ValidateStructure(ctx, client)
+	} else {
+		old := &contractorv1.Structure{Spec: *structure.Status.LastAppliedSpec, Status: structure.Status}
+		errs = structure.ValidateChanges(ctx, client, old)
+	}
+	if invalid := utilerrors.NewAggregate(errs); invalid != nil {
+		return r.setInvalidSpec(ctx, structure, invalid)
+	}
+
+	structure.Status.LastAppliedSpec = lastApplied
+	if meta.FindStatusCondition(structure.Status.Conditions, contractorv1.ConditionInvalidSpec) != nil {
+		meta.SetStatusCondition(&structure.Status.Conditions, metav1.Condition{
+			Type:               contractorv1.ConditionInvalidSpec,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Validated",
+			ObservedGeneration: structure.Generation,
+		})
+	}
+	err := r.Status().Update(ctx, structure)
+	if apierrors.IsConflict(err) {
+		logger.Info("Structure Changed on us, will try again")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "update status faild")
+	}
+
+	logger.Info("Structure spec validated")
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// setInvalidSpec records why the spec is invalid in the InvalidSpec condition, the structure is not requeued, it
+// will be reconciled again when the spec is changed
+func (r *StructureReconciler) setInvalidSpec(ctx context.Context, structure *contractorv1.Structure, invalid error) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Structure spec is invalid, waiting for it to be changed", "error", invalid.Error())
+
+	if meta.SetStatusCondition(&structure.Status.Conditions, metav1.Condition{
+		Type:               contractorv1.ConditionInvalidSpec,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ValidationFailed",
+		Message:            invalid.Error(),
+		ObservedGeneration: structure.Generation,
+	}) {
+		r.Recorder.Event(structure, "Warning", "InvalidSpec", invalid.Error())
+		err := r.Status().Update(ctx, structure)
+		if apierrors.IsConflict(err) {
+			logger.Info("Structure Changed on us, will try again")
+			return ctrl.Result{Requeue: true}, nil
+		}
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "update status faild")
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setContractorUnavailable records that contractor could not be reached in the structure's conditions, the original error is returned
+// so the reconcile is retried with backoff
+func (r *StructureReconciler) setContractorUnavailable(ctx context.Context, structure *contractorv1.Structure, err error) error {
+	reason := "AuthenticationFailed"
+	if errors.Is(err, contractor.ErrCircuitOpen) {
+		reason = "CircuitOpen"
+	}
+
+	if meta.SetStatusCondition(&structure.Status.Conditions, metav1.Condition{
+		Type:               contractorv1.ConditionContractorAvailable,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: structure.Generation,
+	}) {
+		r.Recorder.Event(structure, "Warning", "ContractorUnavailable", err.Error())
+		if err := r.Status().Update(ctx, structure); err != nil && !apierrors.IsConflict(err) {
+			log.FromContext(ctx).Error(err, "updating contractor available condition failed")
+		}
+	}
+
+	return errors.Wrap(err, "contractor unavailable")
 }
 
-func updateStructureStatus(structure *cclient.BuildingStructure, status *contractorv1.StructureStatus) {
+func updateStatus(state *contractor.StructureState, status *contractorv1.StructureStatus, sensitiveKeys []string) {
+	updateStructureStatus(state.Structure, status, sensitiveKeys)
+
+	updateFoundationStatus(state.Foundation, status)
+
+	status.EffectiveConfig = contractorv1.ConfigValuesFromContractor(state.Config).Redact(sensitiveKeys)
+
+	if state.Job == nil {
+		status.Job = nil
+		return
+	}
+
+	updateJobStatus(state.Job, status)
+}
+
+func updateStructureStatus(structure *cclient.BuildingStructure, status *contractorv1.StructureStatus, sensitiveKeys []string) {
 	status.State = *structure.State
 	status.Hostname = *structure.Hostname
 	status.BluePrint = strings.Split(*structure.Blueprint, ":")[1]
 	status.Foundation = *structure.Foundation
-	status.ConfigValues = contractorv1.ConfigValuesFromContractor(*structure.ConfigValues)
+	status.ConfigValues = contractorv1.ConfigValuesFromContractor(*structure.ConfigValues).Redact(sensitiveKeys)
 }
 
 func updateFoundationStatus(foundation *cclient.BuildingFoundation, status *contractorv1.StructureStatus) {
 	status.Foundation = *foundation.Locator
 	status.FoundationBluePrint = strings.Split(*foundation.Blueprint, ":")[1]
+	if foundation.Type != nil {
+		status.FoundationType = *foundation.Type
+	}
 }
 
 func updateJobStatus(job *cclient.ForemanStructureJob, status *contractorv1.StructureStatus) {
@@ -344,5 +709,3 @@ func updateJobStatus(job *cclient.ForemanStructureJob, status *contractorv1.Stru
 		status.Job.MaxTimeRemaining = ""
 	}
 }
-
-// TODO: also need events
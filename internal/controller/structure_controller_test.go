@@ -26,8 +26,11 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	contractorClient "github.com/t3kton/contractor_goclient"
@@ -83,6 +86,8 @@ var _ = Describe("Structure Controller", func() {
 			uri                                               *cinp.URI
 			doGetStructure, doUpdateStructure, doGetFoudation *gomock.Call
 			doCreateCall, doDestroyCall, doGetJob, doFindJob  *gomock.Call
+			doGetConfig                                       *gomock.Call
+			mockConfig                                        map[string]interface{}
 		)
 
 		ctx := context.Background()
@@ -97,7 +102,8 @@ var _ = Describe("Structure Controller", func() {
 			mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
 			Expect(contractor.SetupTestingFactory(ctx, mockCINP)).NotTo(HaveOccurred())
 
-			client := contractor.GetClient(ctx)
+			client, err := contractor.GetClient(ctx)
+			Expect(err).NotTo(HaveOccurred())
 
 			mockStructureState = "planned"
 
@@ -126,7 +132,6 @@ var _ = Describe("Structure Controller", func() {
 			mockJob.Created = TimeAddr(time.Now())
 			mockJob.Updated = TimeAddr(time.Now())
 
-			var err error
 			uri, err = cinp.NewURI("/api/v1/")
 			Expect(err).NotTo(HaveOccurred())
 
@@ -176,6 +181,15 @@ var _ = Describe("Structure Controller", func() {
 					return nil
 				})
 
+			// testing Get Config
+			mockConfig = map[string]interface{}{"hostname": "testing", "site": "test"}
+			doGetConfig = mockCINP.EXPECT().
+				Call(gomock.Any(), gomock.Eq("/api/v1/Building/Structure:42:(getConfig)"), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ string, _ *map[string]interface{}, result *map[string]interface{}) error {
+					*result = mockConfig
+					return nil
+				})
+
 			// testing DoCreate
 			doCreateCall = mockCINP.EXPECT().
 				Call(gomock.Any(), gomock.Eq("/api/v1/Building/Structure:42:(doCreate)"), gomock.Any(), gomock.Any()).
@@ -232,6 +246,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(0)
 			doGetJob.Times(0)
 			doFindJob.Times(0)
+			doGetConfig.Times(0)
 			doCreateCall.Times(0)
 			doDestroyCall.Times(0)
 
@@ -288,6 +303,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(2)
 			doGetJob.Times(0)
 			doFindJob.Times(2)
+			doGetConfig.Times(2)
 			doCreateCall.Times(0)
 			doDestroyCall.Times(0)
 
@@ -357,6 +373,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(2)
 			doGetJob.Times(0)
 			doFindJob.Times(2)
+			doGetConfig.Times(2)
 			doCreateCall.Times(0)
 			doDestroyCall.Times(0)
 
@@ -417,6 +434,10 @@ var _ = Describe("Structure Controller", func() {
 				Hostname:            "testing",
 				Foundation:          "test",
 				FoundationBluePrint: "test-foundation-base",
+				EffectiveConfig: contractorv1.ConfigValues{
+					"hostname": contractorv1.NewConfigValue("testing"),
+					"site":     contractorv1.NewConfigValue("test"),
+				},
 			}
 			Expect(k8sClient.Status().Update(ctx, structure)).To(Succeed())
 
@@ -435,6 +456,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(6)
 			doGetJob.Times(2)
 			doFindJob.Times(6)
+			doGetConfig.Times(6)
 			doCreateCall.Times(1)
 			doDestroyCall.Times(0)
 
@@ -540,6 +562,10 @@ var _ = Describe("Structure Controller", func() {
 				Hostname:            "testing",
 				Foundation:          "test",
 				FoundationBluePrint: "test-foundation-base",
+				EffectiveConfig: contractorv1.ConfigValues{
+					"hostname": contractorv1.NewConfigValue("testing"),
+					"site":     contractorv1.NewConfigValue("test"),
+				},
 			}
 			Expect(k8sClient.Status().Update(ctx, structure)).To(Succeed())
 
@@ -558,6 +584,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(6)
 			doGetJob.Times(2)
 			doFindJob.Times(6)
+			doGetConfig.Times(6)
 			doCreateCall.Times(0)
 			doDestroyCall.Times(1)
 
@@ -663,6 +690,10 @@ var _ = Describe("Structure Controller", func() {
 				Hostname:            "testing",
 				Foundation:          "test",
 				FoundationBluePrint: "test-foundation-base",
+				EffectiveConfig: contractorv1.ConfigValues{
+					"hostname": contractorv1.NewConfigValue("testing"),
+					"site":     contractorv1.NewConfigValue("test"),
+				},
 			}
 			Expect(k8sClient.Status().Update(ctx, structure)).To(Succeed())
 
@@ -680,6 +711,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(4)
 			doGetJob.Times(4)
 			doFindJob.Times(4)
+			doGetConfig.Times(4)
 			doCreateCall.Times(0)
 			doDestroyCall.Times(0)
 
@@ -762,6 +794,10 @@ var _ = Describe("Structure Controller", func() {
 				Hostname:            "testing",
 				Foundation:          "test",
 				FoundationBluePrint: "test-foundation-base",
+				EffectiveConfig: contractorv1.ConfigValues{
+					"hostname": contractorv1.NewConfigValue("testing"),
+					"site":     contractorv1.NewConfigValue("test"),
+				},
 			}
 			Expect(k8sClient.Status().Update(ctx, structure)).To(Succeed())
 
@@ -780,6 +816,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(4)
 			doGetJob.Times(4)
 			doFindJob.Times(4)
+			doGetConfig.Times(4)
 			doCreateCall.Times(0)
 			doDestroyCall.Times(0)
 
@@ -863,6 +900,10 @@ var _ = Describe("Structure Controller", func() {
 				Hostname:            "testing",
 				Foundation:          "test",
 				FoundationBluePrint: "test-foundation-base",
+				EffectiveConfig: contractorv1.ConfigValues{
+					"hostname": contractorv1.NewConfigValue("testing"),
+					"site":     contractorv1.NewConfigValue("test"),
+				},
 			}
 			Expect(k8sClient.Status().Update(ctx, structure)).To(Succeed())
 
@@ -880,6 +921,7 @@ var _ = Describe("Structure Controller", func() {
 			doGetFoudation.Times(2)
 			doGetJob.Times(0)
 			doFindJob.Times(2)
+			doGetConfig.Times(2)
 			doCreateCall.Times(0)
 			doDestroyCall.Times(0)
 
@@ -917,5 +959,635 @@ var _ = Describe("Structure Controller", func() {
 			Expect(structure2.Status.Job).To(BeNil())
 			Expect(structure2.Spec.ConfigValues).ToNot(BeNil())
 		})
+
+		It("should redact sensitive configuration values in the status", func() {
+			By("creating the custom resource for the Kind Structure")
+			var structure2 contractorv1.Structure
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "planned",
+					BluePrint: "test-structure-base",
+					ConfigValues: contractorv1.ConfigValues{
+						"hostname":       contractorv1.NewConfigValue("bob"),
+						"root_password":  contractorv1.NewConfigValue("hunter2"),
+						"snmp_community": contractorv1.NewConfigValue("public"),
+					},
+					SensitiveKeys: []string{"snmp_community"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			controllerReconciler := &StructureReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: &record.FakeRecorder{},
+			}
+
+			mockStructure.ConfigValues = &map[string]interface{}{"hostname": "bob", "root_password": "hunter2", "snmp_community": "public"}
+			mockConfig = map[string]interface{}{"hostname": "bob", "root_password": "hunter2", "site": "test"}
+			mockJobID = 0
+
+			doGetStructure.Times(2)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(2)
+			doGetJob.Times(0)
+			doFindJob.Times(2)
+			doGetConfig.Times(2)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("Reconciling") // this will fill in the status
+			result, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			By("Checking Status")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(structure2.Status.ConfigValues).To(HaveLen(3))
+			Expect(structure2.Status.ConfigValues["hostname"]).To(Equal(contractorv1.NewConfigValue("bob")))
+			Expect(structure2.Status.ConfigValues["root_password"].String()).To(HavePrefix(contractorv1.RedactedPrefix))
+			Expect(structure2.Status.ConfigValues["snmp_community"].String()).To(HavePrefix(contractorv1.RedactedPrefix))
+			Expect(structure2.Status.EffectiveConfig).To(HaveLen(3))
+			Expect(structure2.Status.EffectiveConfig["site"]).To(Equal(contractorv1.NewConfigValue("test")))
+			Expect(structure2.Status.EffectiveConfig["root_password"].String()).To(HavePrefix(contractorv1.RedactedPrefix))
+
+			By("Reconciling Again") // the hashes match, so the config values are not pushed
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(Equal(true))
+		})
+
+		It("should layer config profiles under the structure's config values", func() {
+			By("creating the config profiles")
+			profile1 := &contractorv1.ConfigProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ntp",
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.ConfigProfileSpec{
+					ConfigValues: contractorv1.ConfigValues{
+						"ntp_servers": contractorv1.NewConfigValue([]any{"ntp1", "ntp2"}),
+						"proxy":       contractorv1.NewConfigValue("http://proxy1"),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, profile1)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, profile1)).To(Succeed())
+			}()
+
+			profile2 := &contractorv1.ConfigProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "proxy",
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.ConfigProfileSpec{
+					ConfigValues: contractorv1.ConfigValues{
+						"proxy": contractorv1.NewConfigValue("http://proxy2"),
+						"repo":  contractorv1.NewConfigValue("http://repo"),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, profile2)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, profile2)).To(Succeed())
+			}()
+
+			By("creating the custom resource for the Kind Structure")
+			var structure2 contractorv1.Structure
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "planned",
+					BluePrint: "test-structure-base",
+					ConfigValues: contractorv1.ConfigValues{
+						"repo": contractorv1.NewConfigValue("http://my-repo"),
+					},
+					Profiles: []string{"ntp", "proxy"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			controllerReconciler := &StructureReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: &record.FakeRecorder{},
+			}
+
+			mockJobID = 0
+
+			doGetStructure.Times(5)
+			doUpdateStructure.Times(1)
+			doGetFoudation.Times(5)
+			doGetConfig.Times(5)
+			doGetJob.Times(0)
+			doFindJob.Times(5)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			var pushedValues map[string]interface{}
+			doUpdateStructure.DoAndReturn(func(_ context.Context, object *contractorClient.BuildingStructure) (*cinp.Object, error) {
+				pushedValues = *object.ConfigValues
+				mockStructure.ConfigValues = object.ConfigValues
+				result := cinp.Object(mockStructure)
+				return &result, nil
+			})
+
+			By("Reconciling") // this will fill in the status
+			result, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			By("Reconciling") // push the merged config values
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+			Expect(pushedValues).To(Equal(map[string]interface{}{
+				"ntp_servers": []any{"ntp1", "ntp2"},
+				"proxy":       "http://proxy2",
+				"repo":        "http://my-repo",
+			}))
+
+			By("Reconciling") // pick up the new config values
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			By("Reconciling") // record the applied profiles
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			By("Checking Status")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(structure2.Status.ConfigValues).To(HaveLen(3))
+			Expect(structure2.Status.Profiles).To(Equal([]contractorv1.AppliedProfile{
+				{Name: "ntp", Generation: profile1.Generation},
+				{Name: "proxy", Generation: profile2.Generation},
+			}))
+
+			By("Reconciling") // nothing left to do
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(Equal(true))
+		})
+
+		It("should fail when a config profile is missing", func() {
+			By("creating the custom resource for the Kind Structure")
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "planned",
+					BluePrint: "test-structure-base",
+					Profiles:  []string{"not-there"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			controllerReconciler := &StructureReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: &record.FakeRecorder{},
+			}
+
+			doGetStructure.Times(0)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetConfig.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("Reconciling")
+			_, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).To(MatchError(ContainSubstring("get config profile 'not-there' faild")))
+		})
+
+		It("should fail when a config profile has an invalid config value name", func() {
+			By("creating the custom resources")
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			profile := &contractorv1.ConfigProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bad-names",
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.ConfigProfileSpec{
+					ConfigValues: contractorv1.ConfigValues{"bad name": contractorv1.NewConfigValue("x")},
+				},
+			}
+			Expect(k8sClient.Create(ctx, profile)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, profile)).To(Succeed())
+			}()
+
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "planned",
+					BluePrint: "test-structure-base",
+					Profiles:  []string{"bad-names"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StructureReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			doGetStructure.Times(0)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetConfig.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("Reconciling")
+			_, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).To(MatchError(ContainSubstring("config profile 'bad-names' is invalid")))
+			Expect(recorder.Events).To(Receive(Equal("Warning InvalidProfile config profile 'bad-names' is invalid: invalid configuration value name 'bad name'")))
+		})
+
+		It("should record the force delete and remove the finalizer when the structure is deleted", func() {
+			By("creating the custom resource for the Kind Structure")
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+					Annotations: map[string]string{
+						contractorv1.ForceDeleteAnnotation:   "gone from contractor",
+						contractorv1.ForceDeleteByAnnotation: "alice",
+					},
+					Finalizers: []string{contractorv1.ForceDeleteFinalizer},
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "built",
+					BluePrint: "test-structure-base",
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StructureReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			doGetStructure.Times(0)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetConfig.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("deleting the structure")
+			Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, structure)).To(Succeed())
+			Expect(recorder.Events).NotTo(Receive())
+
+			By("Reconciling")
+			_, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(Receive(Equal("Warning ForceDeleted force deleted, requested by 'alice': gone from contractor")))
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, structure))).To(BeTrue())
+		})
+
+		It("should wait for the job poller instead of polling when there is a job", func() {
+			By("creating the custom resource for the Kind Structure")
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "built",
+					BluePrint: "test-structure-base",
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			structure.Status = contractorv1.StructureStatus{
+				State:               "planned",
+				BluePrint:           "test-structure-base",
+				Hostname:            "testing",
+				Foundation:          "test",
+				FoundationBluePrint: "test-foundation-base",
+				EffectiveConfig: contractorv1.ConfigValues{
+					"hostname": contractorv1.NewConfigValue("testing"),
+					"site":     contractorv1.NewConfigValue("test"),
+				},
+				Job: &contractorv1.JobStatus{
+					State:       "waiting",
+					Script:      "Create",
+					Message:     "Just doing the thing",
+					CanStart:    "true",
+					Created:     mockJob.Created.Format(time.RFC3339),
+					LastUpdated: mockJob.Updated.Format(time.RFC3339),
+					Progress:    "0",
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, structure)).To(Succeed())
+
+			controllerReconciler := &StructureReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Recorder:  &record.FakeRecorder{},
+				JobEvents: make(chan event.TypedGenericEvent[int]),
+			}
+
+			doGetStructure.Times(1)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(1)
+			doGetConfig.Times(1)
+			doGetJob.Times(1)
+			doFindJob.Times(1)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("Reconciling")
+			result, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(jobResyncInterval))
+		})
+
+		It("should clear the consumer consent once it has been used", func() {
+			By("creating the custom resource for the Kind Structure")
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        resourceName,
+					Namespace:   namespaceName,
+					Annotations: map[string]string{contractorv1.ConsumerConsentAnnotation: "1234"},
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "planned",
+					BluePrint: "test-structure-base",
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			controllerReconciler := &StructureReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: &record.FakeRecorder{},
+			}
+
+			doGetStructure.Times(0)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetConfig.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("Reconciling removes the annotation without touching contractor")
+			result, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			var structure2 contractorv1.Structure
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(structure2.Annotations).NotTo(HaveKey(contractorv1.ConsumerConsentAnnotation))
+		})
+
+		It("should re-validate a spec admitted while contractor was unavailable", func() {
+			By("creating the custom resource for the Kind Structure")
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        resourceName,
+					Namespace:   namespaceName,
+					Annotations: map[string]string{contractorv1.UnvalidatedAnnotation: "2025-01-01T00:00:00Z"},
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:        42,
+					State:     "planned",
+					BluePrint: "test-structure-base",
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StructureReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			mockJobID = 0
+			client, err := contractor.GetClient(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			mockBluePrint := client.BlueprintStructureBluePrintNewWithID("test-structure-base")
+			mockBluePrint.FoundationBlueprintList = &[]string{"/api/v1/BluePrint/FoundationBluePrint:test-foundation-base:"}
+			mockCINP.EXPECT().
+				Get(gomock.Any(), gomock.Eq("/api/v1/BluePrint/StructureBluePrint:test-structure-base:")).
+				Return(nil, fmt.Errorf("Not found"))
+
+			doGetStructure.Times(3)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(2)
+			doGetJob.Times(0)
+			doFindJob.Times(1)
+			doGetConfig.Times(1)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("Reconciling with a blueprint contractor does not have")
+			result, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(Equal(true))
+			Expect(recorder.Events).To(Receive(Equal("Warning InvalidSpec blueprint not found")))
+
+			var structure2 contractorv1.Structure
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(meta.IsStatusConditionTrue(structure2.Status.Conditions, contractorv1.ConditionInvalidSpec)).To(BeTrue())
+			Expect(structure2.Status.State).To(BeZero())
+
+			By("Reconciling once the blueprint is there")
+			mockCINP.EXPECT().
+				Get(gomock.Any(), gomock.Eq("/api/v1/BluePrint/StructureBluePrint:test-structure-base:")).
+				DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+					result := cinp.Object(mockBluePrint)
+					return &result, nil
+				})
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(structure2.Annotations).NotTo(HaveKey(contractorv1.UnvalidatedAnnotation))
+
+			By("Reconciling clears the condition")
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(meta.IsStatusConditionFalse(structure2.Status.Conditions, contractorv1.ConditionInvalidSpec)).To(BeTrue())
+			Expect(structure2.Status.State).To(Equal("planned"))
+		})
+
+		It("should validate the spec itself when the webhooks are disabled", func() {
+			By("creating the custom resource for the Kind Structure")
+			req := reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespaceName,
+				},
+				Spec: contractorv1.StructureSpec{
+					ID:           42,
+					State:        "planned",
+					BluePrint:    "test-structure-base",
+					ConfigValues: contractorv1.ConfigValues{"bad name": contractorv1.NewConfigValue(1)},
+				},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			defer func() {
+				By("Cleanup the specific resource instance Structure")
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			}()
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &StructureReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     recorder,
+				ValidateSpec: true,
+			}
+
+			mockJobID = 0
+			client, err := contractor.GetClient(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			mockBluePrint := client.BlueprintStructureBluePrintNewWithID("test-structure-base")
+			mockBluePrint.FoundationBlueprintList = &[]string{"/api/v1/BluePrint/FoundationBluePrint:test-foundation-base:"}
+			mockCINP.EXPECT().
+				Get(gomock.Any(), gomock.Eq("/api/v1/BluePrint/StructureBluePrint:test-structure-base:")).
+				DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+					result := cinp.Object(mockBluePrint)
+					return &result, nil
+				}).Times(2)
+
+			doGetStructure.Times(6)
+			doUpdateStructure.Times(0)
+			doGetFoudation.Times(6)
+			doGetJob.Times(0)
+			doFindJob.Times(4)
+			doGetConfig.Times(4)
+			doCreateCall.Times(0)
+			doDestroyCall.Times(0)
+
+			By("Reconciling") // this will fill in the status
+			result, err := controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			By("Reconciling the invalid spec")
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(Equal(true))
+			Expect(recorder.Events).To(Receive(Equal("Warning InvalidSpec invalid configuration value name 'bad name'")))
+
+			var structure2 contractorv1.Structure
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(meta.IsStatusConditionTrue(structure2.Status.Conditions, contractorv1.ConditionInvalidSpec)).To(BeTrue())
+			Expect(structure2.Status.LastAppliedSpec).To(BeNil())
+
+			By("Reconciling the fixed spec")
+			structure2.Spec.ConfigValues = nil
+			Expect(k8sClient.Update(ctx, &structure2)).To(Succeed())
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(Equal(true))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &structure2)).NotTo(HaveOccurred())
+			Expect(meta.IsStatusConditionFalse(structure2.Status.Conditions, contractorv1.ConditionInvalidSpec)).To(BeTrue())
+			Expect(structure2.Status.LastAppliedSpec).To(Equal(&structure2.Spec))
+
+			By("Reconciling") // nothing left to do
+			result, err = controllerReconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.IsZero()).To(Equal(true))
+		})
 	})
 })
@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	contractorv1 "t3kton.com/api/v1"
+)
+
+// StructureQuotaReconciler keeps the usage in the status of StructureQuotas up to date
+type StructureQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=contractor.t3kton.com,resources=structurequotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=contractor.t3kton.com,resources=structurequotas/status,verbs=get;update;patch
+
+// Reconcile counts the built Structures in the quota's namespace
+func (r *StructureQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var quota contractorv1.StructureQuota
+	err := r.Get(ctx, req.NamespacedName, &quota)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var structures contractorv1.StructureList
+	err = r.List(ctx, &structures, client.InNamespace(quota.Namespace))
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "listing structures faild")
+	}
+
+	usage := quota.Usage(structures.Items)
+	if cmp.Equal(usage, quota.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	quota.Status = usage
+	err = r.Status().Update(ctx, &quota)
+	if apierrors.IsConflict(err) {
+		logger.Info("StructureQuota Changed on us, will try again")
+		return ctrl.Result{Requeue: true}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "update status faild")
+	}
+
+	logger.Info("StructureQuota usage updated", "used", usage.Used)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *StructureQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&contractorv1.StructureQuota{}).
+		Watches(&contractorv1.Structure{}, handler.EnqueueRequestsFromMapFunc(r.quotasForStructure)).
+		Named("structurequota").
+		Complete(r)
+}
+
+// quotasForStructure maps a Structure to the StructureQuotas in it's namespace
+func (r *StructureQuotaReconciler) quotasForStructure(ctx context.Context, obj client.Object) []reconcile.Request {
+	var quotas contractorv1.StructureQuotaList
+	err := r.List(ctx, &quotas, client.InNamespace(obj.GetNamespace()))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "listing structure quotas for structure failed", "structure", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, len(quotas.Items))
+	for i, item := range quotas.Items {
+		requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}}
+	}
+	return requests
+}
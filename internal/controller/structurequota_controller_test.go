@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	contractorv1 "t3kton.com/api/v1"
+)
+
+var _ = Describe("StructureQuota Controller", func() {
+	Context("When reconciling a resource", func() {
+		const namespaceName = "default"
+
+		quotaName := types.NamespacedName{Name: "test-quota", Namespace: namespaceName}
+
+		newStructure := func(name string, id int, state string, statusState string) {
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName},
+				Spec:       contractorv1.StructureSpec{ID: id, State: state, BluePrint: "gpu-worker"},
+			}
+			Expect(k8sClient.Create(ctx, structure)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, structure)).To(Succeed())
+			})
+
+			structure.Status.State = statusState
+			structure.Status.FoundationType = "VCenter"
+			Expect(k8sClient.Status().Update(ctx, structure)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			hard := int32(5)
+			quota := &contractorv1.StructureQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: quotaName.Name, Namespace: namespaceName},
+				Spec: contractorv1.StructureQuotaSpec{
+					Hard:            &hard,
+					BluePrints:      map[string]int32{"gpu-worker": 2, "db": 1},
+					FoundationTypes: map[string]int32{"VCenter": 3},
+				},
+			}
+			Expect(k8sClient.Create(ctx, quota)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, quota)).To(Succeed())
+			})
+		})
+
+		It("should count the built structures in the namespace", func() {
+			newStructure("quota-built", 201, "built", "built")
+			newStructure("quota-building", 202, "built", "planned")
+			newStructure("quota-destroying", 203, "planned", "built")
+			newStructure("quota-planned", 204, "planned", "planned")
+
+			reconciler := &StructureQuotaReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+			Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: quotaName})).To(Equal(reconcile.Result{}))
+
+			quota := &contractorv1.StructureQuota{}
+			Expect(k8sClient.Get(ctx, quotaName, quota)).To(Succeed())
+			Expect(quota.Status.Used).To(Equal(int32(3)))
+			Expect(quota.Status.BluePrints).To(Equal(map[string]int32{"gpu-worker": 3, "db": 0}))
+			Expect(quota.Status.FoundationTypes).To(Equal(map[string]int32{"VCenter": 3}))
+
+			By("mapping structures to the quotas in their namespace")
+			Expect(reconciler.quotasForStructure(ctx, &contractorv1.Structure{ObjectMeta: metav1.ObjectMeta{Namespace: namespaceName}})).To(
+				ConsistOf(reconcile.Request{NamespacedName: quotaName}))
+		})
+	})
+})
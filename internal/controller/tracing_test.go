@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	contractorv1 "t3kton.com/api/v1"
+	"t3kton.com/pkg/tracing"
+)
+
+var _ = Describe("Tracing", func() {
+	var recorder *tracetest.SpanRecorder
+	var previous = otel.GetTracerProvider()
+
+	BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		tracing.SetProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	It("Creates a span per reconcile", func() {
+		structure := &contractorv1.Structure{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tracing"}}
+		reconciler := &StructureReconciler{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(structure).Build()}
+
+		ctx, parent := tracing.Start(context.Background(), "test")
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "tracing"}})
+		Expect(err).To(MatchError("ID Not Specified"))
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "gone"}})
+		Expect(err).NotTo(HaveOccurred())
+		parent.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(3))
+
+		Expect(spans[0].Name()).To(Equal("Structure.Reconcile"))
+		Expect(spans[0].Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+		Expect(spans[0].Attributes()).To(ContainElements(attribute.String("namespace", "default"), attribute.String("name", "tracing")))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+
+		Expect(spans[1].Name()).To(Equal("Structure.Reconcile"))
+		Expect(spans[1].Attributes()).To(ContainElement(attribute.String("name", "gone")))
+		Expect(spans[1].Status().Code).To(Equal(codes.Unset))
+	})
+})
@@ -18,18 +18,28 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 
+	cclient "github.com/t3kton/contractor_goclient"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	apierrors "k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	contractorv1 "t3kton.com/api/v1"
 	"t3kton.com/pkg/contractor"
+	"t3kton.com/pkg/tracing"
 )
 
 func extractID(value string) string {
@@ -43,47 +53,127 @@ func extractID(value string) string {
 // log is for logging in this package.
 var structurelog = logf.Log.WithName("structure-resource")
 
-// SetupStructureWebhookWithManager registers the webhook for Structure in the manager.
-func SetupStructureWebhookWithManager(mgr ctrl.Manager) error {
+// DegradedOptions configures how the Structure webhooks behave when Contractor is unavailable
+type DegradedOptions struct {
+	// Enabled admits changes that do not need Contractor with a warning, and changes that do if there is a cached
+	// lookup of the structure newer than MaxCacheAge, the controller re-validates those once Contractor is available.
+	// When not enabled all changes are rejected while Contractor is unavailable.
+	Enabled bool
+	// MaxCacheAge is how old a cached lookup of the structure can be and still be used to validate a change
+	MaxCacheAge time.Duration
+}
+
+// SetupStructureWebhookWithManager registers the webhook for Structure in the manager, members of consumerAdminGroups
+// can change the ConsumerRef of bound Structures
+func SetupStructureWebhookWithManager(mgr ctrl.Manager, degraded DegradedOptions, consumerAdminGroups []string) error {
 	return ctrl.NewWebhookManagedBy(mgr).For(&contractorv1.Structure{}).
-		WithValidator(&StructureCustomValidator{}).
-		WithDefaulter(&StructureCustomDefaulter{}).
+		WithValidator(&StructureCustomValidator{
+			Client:              mgr.GetClient(),
+			Degraded:            degraded,
+			ConsumerAdminGroups: consumerAdminGroups,
+		}).
+		WithDefaulter(&StructureCustomDefaulter{Degraded: degraded}).
 		Complete()
 }
 
-//+kubebuilder:webhook:path=/mutate-contractor-t3kton-com-v1-structure,mutating=true,failurePolicy=fail,sideEffects=None,groups=contractor.t3kton.com,resources=structures,verbs=create,versions=v1,name=mstructure-v1.kb.io,admissionReviewVersions=v1
+// getContractorClient returns the contractor client, or why contractor is unavailable
+func getContractorClient(ctx context.Context) (*cclient.Contractor, error) {
+	if contractor.CircuitOpen() {
+		return nil, contractor.ErrCircuitOpen
+	}
+
+	client, err := contractor.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to contractor, err: %s", err)
+	}
+
+	return client, nil
+}
+
+//+kubebuilder:webhook:path=/mutate-contractor-t3kton-com-v1-structure,mutating=true,failurePolicy=fail,sideEffects=None,groups=contractor.t3kton.com,resources=structures,verbs=create;update,versions=v1,name=mstructure-v1.kb.io,admissionReviewVersions=v1
 
 // StructureCustomDefaulter struct is responsible for setting default values on the custom resource of the
-// Kind Structure when those are created
+// Kind Structure when those are created, and marking Structures changed while Contractor is unavailable
 type StructureCustomDefaulter struct {
+	Degraded DegradedOptions
 }
 
 var _ webhook.CustomDefaulter = &StructureCustomDefaulter{}
 
 // Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Structure.
-// We will copy the State, BluePrint, and ConfigValues from contractor if they are blank
-func (d *StructureCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+// We will copy the State, BluePrint, and ConfigValues from contractor if they are blank when the Structure is created.
+// In degraded mode, Structures created or updated while contractor is unavailable are marked with the unvalidated
+// annotation so the controller re-validates them.
+func (d *StructureCustomDefaulter) Default(ctx context.Context, obj runtime.Object) (err error) {
+	ctx, span := tracing.Start(ctx, "Structure.Default")
+	defer func() { tracing.End(span, err) }()
+
 	structure, ok := obj.(*contractorv1.Structure)
 	if !ok {
 		return fmt.Errorf("expected an Structure object but got %T", obj)
 	}
 	structurelog.Info("Defaulting for Structure", "name", structure.GetName())
 
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		var old *contractorv1.Structure
+		if len(req.OldObject.Raw) > 0 {
+			old = &contractorv1.Structure{}
+			if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+				return fmt.Errorf("unable to decode the old Structure, err: %s", err)
+			}
+		}
+		structure.RecordBinding(old, req.UserInfo.Username)
+		structure.RecordForceDelete(old, req.UserInfo.Username)
+	}
+
+	var unavailable error
+	if d.Degraded.Enabled {
+		_, unavailable = getContractorClient(ctx)
+		if unavailable != nil {
+			if structure.Annotations == nil {
+				structure.Annotations = map[string]string{}
+			}
+			structure.Annotations[contractorv1.UnvalidatedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		} else {
+			// the validator is about to check the whole spec against contractor
+			delete(structure.Annotations, contractorv1.UnvalidatedAnnotation)
+		}
+	}
+
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation == admissionv1.Update {
+		return nil
+	}
+
 	if structure.Spec.ID == 0 {
 		return fmt.Errorf("ID not set")
 	}
 
-	if structure.Spec.State != "" && structure.Spec.BluePrint != "" && structure.Spec.ConfigValues != nil {
+	// with profiles, contractor's current values would be layered on top of the profiles and override them
+	defaultConfigValues := structure.Spec.ConfigValues == nil && len(structure.Spec.Profiles) == 0
+
+	if structure.Spec.State != "" && structure.Spec.BluePrint != "" && !defaultConfigValues {
 		structurelog.Info("No Defaulting needed")
 		return nil
 	}
 
-	client := contractor.GetClient(ctx)
+	var upstreamStructure *cclient.BuildingStructure
+	if unavailable != nil {
+		state, ok := contractor.CachedStructureState(structure.Spec.ID, d.Degraded.MaxCacheAge)
+		if !ok {
+			return fmt.Errorf("%s, and there is no lookup of structure '%d' cached in the last %s to default from", unavailable, structure.Spec.ID, d.Degraded.MaxCacheAge)
+		}
+		structurelog.Info("Using cached Structure")
+		upstreamStructure = state.Structure
+	} else {
+		if contractor.CircuitOpen() {
+			return contractor.ErrCircuitOpen
+		}
 
-	structurelog.Info("Getting Structure")
-	upstreamStructure, err := client.BuildingStructureGet(ctx, structure.Spec.ID)
-	if err != nil {
-		return fmt.Errorf("unable to get structure '%d', err: %s", structure.Spec.ID, err)
+		structurelog.Info("Getting Structure")
+		upstreamStructure, err = contractor.GetStructure(ctx, structure.Spec.ID)
+		if err != nil {
+			return fmt.Errorf("unable to get structure '%d', err: %s", structure.Spec.ID, err)
+		}
 	}
 
 	// State, Blueprint, configvalues should come from current contractor state if they are not set
@@ -97,12 +187,18 @@ func (d *StructureCustomDefaulter) Default(ctx context.Context, obj runtime.Obje
 		structure.Spec.BluePrint = extractID(*upstreamStructure.Blueprint)
 	}
 
-	if structure.Spec.ConfigValues == nil {
-		structurelog.Info("setting", "config values", *upstreamStructure.ConfigValues)
+	if defaultConfigValues {
+		// sensitive values are left out, copying them would put them in the spec in plain text
 		structure.Spec.ConfigValues = make(map[string]contractorv1.ConfigValue, len(*upstreamStructure.ConfigValues))
 		for key, val := range *upstreamStructure.ConfigValues {
+			if contractorv1.IsSensitiveKey(key, structure.Spec.SensitiveKeys) {
+				structurelog.Info("not setting sensitive config value", "name", key)
+				continue
+			}
 			structure.Spec.ConfigValues[key] = contractorv1.ConfigValueFromContractor(val)
 		}
+		redacted := structure.Spec.ConfigValues.Redact(structure.Spec.SensitiveKeys)
+		structurelog.Info("setting", "config values", redacted.Value())
 	}
 
 	return nil
@@ -110,28 +206,93 @@ func (d *StructureCustomDefaulter) Default(ctx context.Context, obj runtime.Obje
 
 // +kubebuilder:webhook:path=/validate-contractor-t3kton-com-v1-structure,mutating=false,failurePolicy=fail,sideEffects=None,groups=contractor.t3kton.com,resources=structures,verbs=create;update;delete,versions=v1,name=vstructure-v1.kb.io,admissionReviewVersions=v1
 
+// +kubebuilder:rbac:groups=contractor.t3kton.com,resources=structurepolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=contractor.t3kton.com,resources=configprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=contractor.t3kton.com,resources=structurequotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
 // StructureCustomValidator struct is responsible for validating the Structure resource
 // when it is created, updated, or deleted.
 type StructureCustomValidator struct {
-	// TODO(user): Add more fields as needed for validation
+	// Client is used to look up the StructurePolicies bound to the Structure's namespace and the StructureQuotas in it,
+	// if nil policies and quotas are not enforced.  Also used to get the ConfigProfiles for dry run plans.
+	Client client.Reader
+	// Degraded configures what is admitted while contractor is unavailable
+	Degraded DegradedOptions
+	// ConsumerAdminGroups are the groups who's members can change the ConsumerRef of a bound Structure
+	ConsumerAdminGroups []string
 }
 
 var _ webhook.CustomValidator = &StructureCustomValidator{}
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Structure.
-func (v *StructureCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+func (v *StructureCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (warnings admission.Warnings, err error) {
+	ctx, span := tracing.Start(ctx, "Structure.ValidateCreate")
+	defer func() { tracing.End(span, err) }()
+
 	structure, ok := obj.(*contractorv1.Structure)
 	if !ok {
 		return nil, fmt.Errorf("expected a Structure object but got %T", obj)
 	}
 	structurelog.Info("Validation for Structure upon creation", "name", structure.GetName())
 
-	client := contractor.GetClient(ctx)
-	return nil, apierrors.NewAggregate(structure.ValidateStructure(ctx, client))
+	if err := apierrors.NewAggregate(structure.ValidateConsumer(nil, requester(ctx), v.consumerAdmin(ctx))); err != nil {
+		return nil, err
+	}
+
+	client, err := getContractorClient(ctx)
+	if err != nil {
+		return v.admitDegraded(ctx, structure, nil, err)
+	}
+	if err := apierrors.NewAggregate(structure.ValidateStructure(ctx, client)); err != nil {
+		return nil, err
+	}
+
+	upstreamStructure, err := contractor.GetStructure(ctx, structure.Spec.ID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get structure from contractor, err: %s", err)
+	}
+	current := contractorv1.StructureStatus{}
+	if upstreamStructure.State != nil {
+		current.State = *upstreamStructure.State
+	}
+	if upstreamStructure.Hostname != nil {
+		current.Hostname = *upstreamStructure.Hostname
+	}
+	if upstreamStructure.Blueprint != nil {
+		current.BluePrint = extractID(*upstreamStructure.Blueprint)
+	}
+	if upstreamStructure.ConfigValues != nil {
+		current.ConfigValues = contractorv1.ConfigValuesFromContractor(*upstreamStructure.ConfigValues).Redact(structure.Spec.SensitiveKeys)
+	}
+
+	if err := v.checkPolicies(ctx, structure, current.State, policyFoundation); err != nil {
+		return nil, err
+	}
+
+	if structure.Spec.State == "built" {
+		if err := v.checkQuotas(ctx, structure, policyFoundation); err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun(ctx) {
+		return v.plan(ctx, structure, current), nil
+	}
+
+	desired, err := v.desiredConfigValues(ctx, structure)
+	if err != nil {
+		return admission.Warnings{err.Error()}, nil
+	}
+
+	return structure.ChangeWarnings(nil, desired, current), nil
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Structure.
-func (v *StructureCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+func (v *StructureCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (warnings admission.Warnings, err error) {
+	ctx, span := tracing.Start(ctx, "Structure.ValidateUpdate")
+	defer func() { tracing.End(span, err) }()
+
 	newStructure, ok := newObj.(*contractorv1.Structure)
 	if !ok {
 		return nil, fmt.Errorf("expected a Structure object for the newObj but got %T", newObj)
@@ -143,8 +304,315 @@ func (v *StructureCustomValidator) ValidateUpdate(ctx context.Context, oldObj, n
 		return nil, fmt.Errorf("expected a Structure object for the oldObj but got %T", oldObj)
 	}
 
-	client := contractor.GetClient(ctx)
-	return nil, apierrors.NewAggregate(newStructure.ValidateChanges(ctx, client, oldStructure))
+	if err := apierrors.NewAggregate(newStructure.ValidateConsumer(oldStructure, requester(ctx), v.consumerAdmin(ctx))); err != nil {
+		return nil, err
+	}
+
+	// force deleting is for when the structure is gone from contractor, so setting the annotation can not depend on contractor
+	if _, force := newStructure.ForceDeleteReason(); force && reflect.DeepEqual(newStructure.Spec, oldStructure.Spec) {
+		return nil, nil
+	}
+
+	client, err := getContractorClient(ctx)
+	if err != nil {
+		return v.admitDegraded(ctx, newStructure, oldStructure, err)
+	}
+	if err := apierrors.NewAggregate(newStructure.ValidateChanges(ctx, client, oldStructure)); err != nil {
+		return nil, err
+	}
+
+	// only changes to the spec are held to the policies, so metadata changes, ie: removing finalizers, are not
+	// blocked on structures that were created before a policy was
+	if !reflect.DeepEqual(newStructure.Spec, oldStructure.Spec) {
+		// a structure with no requested state stays in the state it is in on contractor, so setting the state after
+		// clearing it is the same transition as changing it directly
+		fromState := oldStructure.Spec.State
+		if fromState == "" && newStructure.Spec.State != "" {
+			fromState = oldStructure.Status.State
+		}
+		if err := v.checkPolicies(ctx, newStructure, fromState, policyFoundation); err != nil {
+			return nil, err
+		}
+	}
+
+	if newStructure.Spec.State == "built" && !oldStructure.ConsumesQuota() {
+		if err := v.checkQuotas(ctx, newStructure, policyFoundation); err != nil {
+			return nil, err
+		}
+	}
+
+	// the status is kept in sync with contractor by the controller
+	if dryRun(ctx) {
+		current := oldStructure.Status
+		// the status is redacted with the old SensitiveKeys, the controller compares contractor's values redacted with the new ones
+		if !slices.Equal(newStructure.Spec.SensitiveKeys, oldStructure.Spec.SensitiveKeys) {
+			upstreamStructure, err := contractor.GetStructure(ctx, newStructure.Spec.ID)
+			if err != nil {
+				return admission.Warnings{fmt.Sprintf("plan: unable to get structure from contractor, err: %s", err)}, nil
+			}
+			current.ConfigValues = nil
+			if upstreamStructure.ConfigValues != nil {
+				current.ConfigValues = contractorv1.ConfigValuesFromContractor(*upstreamStructure.ConfigValues).Redact(newStructure.Spec.SensitiveKeys)
+			}
+		}
+		return v.plan(ctx, newStructure, current), nil
+	}
+
+	return newStructure.ChangeWarnings(oldStructure, newStructure.Spec.ConfigValues, oldStructure.Status), nil
+}
+
+// dryRun is true if the request will not be persisted, ie: kubectl apply --dry-run=server
+func dryRun(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && req.DryRun != nil && *req.DryRun
+}
+
+// plan describes what the controller would do on contractor if the structure was applied, current is what
+// contractor currently has
+func (v *StructureCustomValidator) plan(ctx context.Context, structure *contractorv1.Structure, current contractorv1.StructureStatus) admission.Warnings {
+	desired, err := v.desiredConfigValues(ctx, structure)
+	if err != nil {
+		return admission.Warnings{"plan: " + err.Error()}
+	}
+
+	return structure.Plan(desired, current)
+}
+
+// desiredConfigValues is the structure's config values with it's profiles layered under them, the same as the
+// controller pushes to contractor
+func (v *StructureCustomValidator) desiredConfigValues(ctx context.Context, structure *contractorv1.Structure) (contractorv1.ConfigValues, error) {
+	if len(structure.Spec.Profiles) == 0 || v.Client == nil {
+		return structure.Spec.ConfigValues, nil
+	}
+
+	layers := make([]contractorv1.ConfigValues, 0, len(structure.Spec.Profiles)+1)
+	for _, name := range structure.Spec.Profiles {
+		profile := &contractorv1.ConfigProfile{}
+		if err := v.Client.Get(ctx, types.NamespacedName{Namespace: structure.Namespace, Name: name}, profile); err != nil {
+			return nil, fmt.Errorf("unable to get config profile '%s', the structure will not be reconciled until it can be, err: %s", name, err)
+		}
+		layers = append(layers, profile.Spec.ConfigValues)
+	}
+
+	return contractorv1.MergeConfigValues(append(layers, structure.Spec.ConfigValues)...), nil
+}
+
+// requester is the user making the request, blank if it is not known
+func requester(ctx context.Context) string {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return ""
+	}
+
+	return req.UserInfo.Username
+}
+
+// consumerAdmin is true if the user making the request is in one of the ConsumerAdminGroups
+func (v *StructureCustomValidator) consumerAdmin(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(req.UserInfo.Groups, func(group string) bool { return slices.Contains(v.ConsumerAdminGroups, group) })
+}
+
+// admitDegraded validates a structure while contractor is unavailable, unavailable is why.  Changes that only touch
+// the metadata or ConsumerRef are admitted, other changes are checked against a recent cached lookup of the structure
+// and are re-validated by the controller once contractor is available, old is nil when the structure is being created
+func (v *StructureCustomValidator) admitDegraded(ctx context.Context, structure *contractorv1.Structure, old *contractorv1.Structure, unavailable error) (admission.Warnings, error) {
+	if !v.Degraded.Enabled {
+		return nil, unavailable
+	}
+
+	if old != nil && !needsContractor(old, structure) {
+		return admission.Warnings{fmt.Sprintf("%s, the change was admitted without checking contractor", unavailable)}, nil
+	}
+
+	state, ok := contractor.CachedStructureState(structure.Spec.ID, v.Degraded.MaxCacheAge)
+	if !ok {
+		return nil, fmt.Errorf("%s, and there is no lookup of structure '%d' cached in the last %s to validate against", unavailable, structure.Spec.ID, v.Degraded.MaxCacheAge)
+	}
+
+	if err := apierrors.NewAggregate(structure.ValidateOffline(old)); err != nil {
+		return nil, err
+	}
+
+	current := contractorv1.StructureStatus{}
+	if state.Structure.State != nil {
+		current.State = *state.Structure.State
+	}
+	if state.Structure.Hostname != nil {
+		current.Hostname = *state.Structure.Hostname
+	}
+	if state.Structure.ConfigValues != nil {
+		current.ConfigValues = contractorv1.ConfigValuesFromContractor(*state.Structure.ConfigValues).Redact(structure.Spec.SensitiveKeys)
+	}
+	fromState := current.State
+	if old != nil {
+		current = old.Status
+		fromState = old.Spec.State
+	}
+
+	lookup := func(_ context.Context, _ int) (*contractorv1.PolicyFoundation, error) {
+		if state.Foundation == nil {
+			return nil, fmt.Errorf("structure has no foundation")
+		}
+		return foundationFromContractor(state.Foundation), nil
+	}
+
+	if old == nil || !reflect.DeepEqual(structure.Spec, old.Spec) {
+		if err := v.checkPolicies(ctx, structure, fromState, lookup); err != nil {
+			return nil, err
+		}
+	}
+
+	if structure.Spec.State == "built" && (old == nil || !old.ConsumesQuota()) {
+		if err := v.checkQuotas(ctx, structure, lookup); err != nil {
+			return nil, err
+		}
+	}
+
+	warnings := admission.Warnings{fmt.Sprintf("%s, the change was checked against a cached lookup of structure '%d' "+
+		"and will be re-validated once contractor is available", unavailable, structure.Spec.ID)}
+	desired, err := v.desiredConfigValues(ctx, structure)
+	if err != nil {
+		return append(warnings, err.Error()), nil
+	}
+
+	return append(warnings, structure.ChangeWarnings(old, desired, current)...), nil
+}
+
+// needsContractor is true if validating the change from old needs contractor, the metadata and ConsumerRef are not
+// sent to contractor
+func needsContractor(old *contractorv1.Structure, structure *contractorv1.Structure) bool {
+	oldSpec := old.Spec.DeepCopy()
+	oldSpec.ConsumerRef = nil
+	spec := structure.Spec.DeepCopy()
+	spec.ConsumerRef = nil
+
+	return !reflect.DeepEqual(oldSpec, spec)
+}
+
+// foundationLookup gets the site and type of the structure's foundation
+type foundationLookup func(ctx context.Context, id int) (*contractorv1.PolicyFoundation, error)
+
+// checkPolicies checks the structure against the StructurePolicies bound to it's namespace, fromState is the
+// requested state the structure is changing from
+func (v *StructureCustomValidator) checkPolicies(ctx context.Context, structure *contractorv1.Structure, fromState string, lookupFoundation foundationLookup) error {
+	if v.Client == nil {
+		return nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, types.NamespacedName{Name: structure.Namespace}, namespace); err != nil {
+		return fmt.Errorf("unable to get namespace '%s', err: %s", structure.Namespace, err)
+	}
+
+	policyList := &contractorv1.StructurePolicyList{}
+	if err := v.Client.List(ctx, policyList); err != nil {
+		return fmt.Errorf("unable to list StructurePolicies, err: %s", err)
+	}
+
+	policies := []contractorv1.StructurePolicy{}
+	needsFoundation := false
+	for _, policy := range policyList.Items {
+		bound, err := policy.Binds(namespace)
+		if err != nil {
+			return err
+		}
+		if bound {
+			policies = append(policies, policy)
+			needsFoundation = needsFoundation || policy.NeedsFoundation()
+		}
+	}
+
+	var foundation *contractorv1.PolicyFoundation
+	if needsFoundation {
+		var err error
+		foundation, err = lookupFoundation(ctx, structure.Spec.ID)
+		if err != nil {
+			return fmt.Errorf("unable to get the foundation to check StructurePolicies, err: %s", err)
+		}
+	}
+
+	var errs []error
+	for _, policy := range policies {
+		errs = append(errs, policy.CheckStructure(structure, fromState, foundation)...)
+	}
+
+	return apierrors.NewAggregate(errs)
+}
+
+// checkQuotas checks that building the structure would not exceed the StructureQuotas in it's namespace
+func (v *StructureCustomValidator) checkQuotas(ctx context.Context, structure *contractorv1.Structure, lookupFoundation foundationLookup) error {
+	if v.Client == nil {
+		return nil
+	}
+
+	quotaList := &contractorv1.StructureQuotaList{}
+	if err := v.Client.List(ctx, quotaList, client.InNamespace(structure.Namespace)); err != nil {
+		return fmt.Errorf("unable to list StructureQuotas, err: %s", err)
+	}
+	if len(quotaList.Items) == 0 {
+		return nil
+	}
+
+	structureList := &contractorv1.StructureList{}
+	if err := v.Client.List(ctx, structureList, client.InNamespace(structure.Namespace)); err != nil {
+		return fmt.Errorf("unable to list Structures, err: %s", err)
+	}
+	others := slices.DeleteFunc(structureList.Items, func(item contractorv1.Structure) bool { return item.Name == structure.Name })
+
+	foundationType := structure.Status.FoundationType
+	if foundationType == "" && slices.ContainsFunc(quotaList.Items, func(quota contractorv1.StructureQuota) bool { return len(quota.Spec.FoundationTypes) > 0 }) {
+		foundation, err := lookupFoundation(ctx, structure.Spec.ID)
+		if err != nil {
+			return fmt.Errorf("unable to get the foundation to check StructureQuotas, err: %s", err)
+		}
+		foundationType = foundation.Type
+	}
+
+	var errs []error
+	for _, quota := range quotaList.Items {
+		errs = append(errs, quota.Admit(quota.Usage(others), structure, foundationType)...)
+	}
+
+	return apierrors.NewAggregate(errs)
+}
+
+// policyFoundation gets the site and type of the structure's foundation from contractor
+func policyFoundation(ctx context.Context, id int) (*contractorv1.PolicyFoundation, error) {
+	upstreamStructure, err := contractor.GetStructure(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upstreamStructure.Foundation == nil {
+		return nil, fmt.Errorf("structure has no foundation")
+	}
+
+	client, err := contractor.GetClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	upstreamFoundation, err := client.BuildingFoundationGetURI(ctx, *upstreamStructure.Foundation)
+	if err != nil {
+		return nil, err
+	}
+
+	return foundationFromContractor(upstreamFoundation), nil
+}
+
+func foundationFromContractor(upstreamFoundation *cclient.BuildingFoundation) *contractorv1.PolicyFoundation {
+	result := &contractorv1.PolicyFoundation{}
+	if upstreamFoundation.Site != nil {
+		result.Site = extractID(*upstreamFoundation.Site)
+	}
+	if upstreamFoundation.Type != nil {
+		result.Type = *upstreamFoundation.Type
+	}
+
+	return result
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Structure.
@@ -155,5 +623,26 @@ func (v *StructureCustomValidator) ValidateDelete(ctx context.Context, obj runti
 	}
 	structurelog.Info("Validation for Structure upon deletion", "name", structure.GetName())
 
-	return nil, apierrors.NewAggregate(structure.CanDelete(ctx))
+	if err := apierrors.NewAggregate(structure.CanDelete(ctx)); err != nil {
+		return nil, err
+	}
+
+	reason, force := structure.ForceDeleteReason()
+	if !force {
+		return nil, nil
+	}
+
+	username := "unknown"
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		username = req.UserInfo.Username
+	}
+	// the ForceDeleted event is recorded by the controller once the structure is actually deleted
+	structurelog.Info("Structure force deleted", "name", structure.GetName(), "user", username, "reason", reason)
+
+	blockers := structure.DeleteBlockers()
+	if len(blockers) == 0 {
+		return nil, nil
+	}
+
+	return admission.Warnings{fmt.Sprintf("force deleting, the structure on contractor is left as is: %s", apierrors.NewAggregate(blockers))}, nil
 }
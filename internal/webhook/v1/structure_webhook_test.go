@@ -18,7 +18,11 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -26,9 +30,24 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	contractorClient "github.com/t3kton/contractor_goclient"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/mock/gomock"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"t3kton.com/pkg/contractor"
 	"t3kton.com/pkg/contractor/test_contractor"
+	"t3kton.com/pkg/tracing"
 
 	contractorv1 "t3kton.com/api/v1"
 )
@@ -61,7 +80,8 @@ var _ = Describe("Structure Webhook", func() {
 		mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
 		Expect(contractor.SetupTestingFactory(ctx, mockCINP)).NotTo(HaveOccurred())
 
-		client := contractor.GetClient(ctx)
+		client, err := contractor.GetClient(ctx)
+		Expect(err).NotTo(HaveOccurred())
 
 		mockStructureState = "planned"
 
@@ -92,8 +112,8 @@ var _ = Describe("Structure Webhook", func() {
 
 		mockStructureBluePrint = client.BlueprintStructureBluePrintNewWithID("test-structure-base")
 		mockStructureBluePrint.Name = cinp.StringAddr("test-structure-base")
+		mockStructureBluePrint.FoundationBlueprintList = &[]string{"/api/v1/BluePrint/FoundationBluePrint:test-foundation-base:"}
 
-		var err error
 		uri, err = cinp.NewURI("/api/v1/")
 		Expect(err).NotTo(HaveOccurred())
 
@@ -115,7 +135,7 @@ var _ = Describe("Structure Webhook", func() {
 
 		// testing Get Foundation
 		doGetFoudation = mockCINP.EXPECT().
-			Get(gomock.Any(), gomock.Eq("/api/v1/Building/Foundation:testing:")).
+			Get(gomock.Any(), gomock.Eq("/api/v1/Building/Foundation:test:")).
 			DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
 				result := cinp.Object(mockFoundation)
 				return &result, nil
@@ -251,6 +271,24 @@ var _ = Describe("Structure Webhook", func() {
 			Expect(structure.Spec.State).To(Equal("planned"))
 		})
 
+		It("Should not copy sensitive config values into the spec", func() {
+			structure := &contractorv1.Structure{
+				Spec: contractorv1.StructureSpec{ID: 123, SensitiveKeys: []string{"b"}},
+			}
+			mockStructure.ConfigValues = &map[string]interface{}{"a": "asdf", "b": 12, "root_password": "hunter2"}
+
+			doGetStructure.Times(1)
+			doGetFoudation.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(0)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			Expect(defaulter.Default(ctx, structure)).Should(Succeed())
+			Expect(structure.Spec.ConfigValues).To(Equal(contractorv1.ConfigValues{"a": contractorv1.NewConfigValue("asdf")}))
+		})
+
 		It("Should fall through if state, blueprint, configValues are set", func() {
 			By("Defaulter Setup")
 			structure := &contractorv1.Structure{
@@ -288,6 +326,29 @@ var _ = Describe("Structure Webhook", func() {
 			Expect(structure.Spec.State).To(Equal("built"))
 
 		})
+
+		It("Should not default the config values when there are profiles", func() {
+			structure := &contractorv1.Structure{
+				Spec: contractorv1.StructureSpec{ID: 123, Profiles: []string{"common"}},
+			}
+
+			doGetStructure.Times(1)
+			doGetFoudation.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(0)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			Expect(defaulter.Default(ctx, structure)).Should(Succeed())
+			Expect(structure.Spec.BluePrint).To(Equal("test-structure-base"))
+			Expect(structure.Spec.State).To(Equal("planned"))
+			Expect(structure.Spec.ConfigValues).To(BeNil())
+
+			By("not calling contractor when only the config values are missing")
+			doGetStructure.Times(0)
+			Expect(defaulter.Default(ctx, structure)).Should(Succeed())
+		})
 	})
 
 	Context("When creating Structure under Validating Webhook", func() {
@@ -373,7 +434,7 @@ var _ = Describe("Structure Webhook", func() {
 			}
 
 			doGetStructure.Times(1)
-			doGetFoudation.Times(0)
+			doGetFoudation.Times(1)
 			doGetJob.Times(0)
 			doFindJob.Times(0)
 			doGetStructureBluePrint.Times(1)
@@ -416,8 +477,8 @@ var _ = Describe("Structure Webhook", func() {
 				},
 			}
 
-			doGetStructure.Times(1)
-			doGetFoudation.Times(0)
+			doGetStructure.Times(2)
+			doGetFoudation.Times(1)
 			doGetJob.Times(0)
 			doFindJob.Times(0)
 			doGetStructureBluePrint.Times(1)
@@ -449,6 +510,87 @@ var _ = Describe("Structure Webhook", func() {
 			Expect(structure.Spec.ConfigValues["stuff"]).To(Equal(contractorv1.NewConfigValue(1)))
 			Expect(structure.Spec.State).To(Equal(""))
 		})
+		It("Should only warn about pushing config values that differ from contractor's", func() {
+			By("ValidateCreate Setup")
+			mockStructureState = "built"
+			structure := &contractorv1.Structure{
+				Spec: contractorv1.StructureSpec{
+					ID:           123,
+					BluePrint:    "test-structure-base",
+					ConfigValues: contractorv1.ConfigValuesFromContractor(*mockStructure.ConfigValues),
+				},
+			}
+
+			doGetStructure.Times(4)
+			doGetFoudation.Times(2)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(2)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			By("Call ValidateCreate with the values contractor has")
+			warn, err := validator.ValidateCreate(ctx, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(BeEmpty())
+
+			By("Call ValidateCreate with a changed value")
+			structure.Spec.ConfigValues["a"] = contractorv1.NewConfigValue("changed")
+			warn, err = validator.ValidateCreate(ctx, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(Equal(admission.Warnings{"config values will be pushed to the built machine 'testing'"}))
+		})
+		It("Should reject a blueprint that is not compatible with the foundation", func() {
+			By("ValidateCreate Setup")
+			structure := &contractorv1.Structure{
+				Spec: contractorv1.StructureSpec{
+					ID:        123,
+					BluePrint: "test-structure-base",
+				},
+			}
+			mockStructureBluePrint.FoundationBlueprintList = &[]string{
+				"/api/v1/BluePrint/FoundationBluePrint:other-foundation-base:",
+				"/api/v1/BluePrint/FoundationBluePrint:another-foundation-base:",
+			}
+
+			doGetStructure.Times(1)
+			doGetFoudation.Times(1)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(1)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			client, err := contractor.GetClient(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			compatible := client.BlueprintStructureBluePrintNewWithID("test-other-base")
+			compatible.FoundationBlueprintList = &[]string{
+				"/api/v1/BluePrint/FoundationBluePrint:other-foundation-base:",
+				"/api/v1/BluePrint/FoundationBluePrint:test-foundation-base:",
+			}
+			alsoCompatible := client.BlueprintStructureBluePrintNewWithID("test-another-base")
+			alsoCompatible.FoundationBlueprintList = &[]string{"/api/v1/BluePrint/FoundationBluePrint:test-foundation-base:"}
+			incompatible := client.BlueprintStructureBluePrintNewWithID("test-unrelated-base")
+			incompatible.FoundationBlueprintList = &[]string{"/api/v1/BluePrint/FoundationBluePrint:other-foundation-base:"}
+			mockCINP.EXPECT().
+				ListObjects(gomock.Any(), "/api/v1/BluePrint/StructureBluePrint", gomock.Any(), "", gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ string, _ reflect.Type, _ string, _ map[string]interface{}, _ int) <-chan *cinp.Object {
+					result := make(chan *cinp.Object, 4)
+					for _, blueprint := range []*contractorClient.BlueprintStructureBluePrint{mockStructureBluePrint, compatible, incompatible, alsoCompatible} {
+						object := cinp.Object(blueprint)
+						result <- &object
+					}
+					close(result)
+					return result
+				})
+
+			By("Call ValidateCreate")
+			warn, err := validator.ValidateCreate(ctx, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("blueprint 'test-structure-base' is not compatible with the foundation blueprint 'test-foundation-base', " +
+				"compatible structure blueprints: [test-other-base, test-another-base]"))
+		})
 	})
 
 	Context("When changing Structure under Validating Webhook", func() {
@@ -468,7 +610,7 @@ var _ = Describe("Structure Webhook", func() {
 			}
 
 			doGetStructure.Times(1)
-			doGetFoudation.Times(0)
+			doGetFoudation.Times(1)
 			doGetJob.Times(0)
 			doFindJob.Times(0)
 			doGetStructureBluePrint.Times(1)
@@ -597,7 +739,7 @@ var _ = Describe("Structure Webhook", func() {
 		}
 
 		doGetStructure.Times(9)
-		doGetFoudation.Times(0)
+		doGetFoudation.Times(9)
 		doGetJob.Times(0)
 		doFindJob.Times(0)
 		doGetStructureBluePrint.Times(9)
@@ -642,7 +784,8 @@ var _ = Describe("Structure Webhook", func() {
 
 		By("Call ValidateUpdate")
 		warn, err = validator.ValidateUpdate(ctx, oldStructure, structure)
-		Expect(warn).To(BeNil())
+		Expect(warn).To(Equal(admission.Warnings{"blueprint will be changed from 'old-test-structure-base' to 'test-structure-base' on contractor, " +
+			"it will be used when the structure is built"}))
 		Expect(err).To(BeNil())
 
 		By("Setting both Spec State to built")
@@ -699,7 +842,8 @@ var _ = Describe("Structure Webhook", func() {
 
 		By("Call ValidateUpdate")
 		warn, err = validator.ValidateUpdate(ctx, oldStructure, structure)
-		Expect(warn).To(BeNil())
+		Expect(warn).To(Equal(admission.Warnings{"blueprint will be changed from 'old-test-structure-base' to 'test-structure-base' on contractor, " +
+			"it will be used when the structure is built"}))
 		Expect(err).To(BeNil())
 
 		By("Checking Spec After")
@@ -733,7 +877,7 @@ var _ = Describe("Structure Webhook", func() {
 		}
 
 		doGetStructure.Times(4)
-		doGetFoudation.Times(0)
+		doGetFoudation.Times(4)
 		doGetJob.Times(0)
 		doFindJob.Times(0)
 		doGetStructureBluePrint.Times(4)
@@ -748,7 +892,8 @@ var _ = Describe("Structure Webhook", func() {
 
 		By("Call ValidateUpdate")
 		warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
-		Expect(warn).To(BeNil())
+		Expect(warn).To(Equal(admission.Warnings{"blueprint will be changed from 'old-test-structure-base' to 'test-structure-base' on contractor, " +
+			"it will be used when the structure is built"}))
 		Expect(err).To(BeNil())
 
 		structure.Status.Job = &contractorv1.JobStatus{}
@@ -807,7 +952,7 @@ var _ = Describe("Structure Webhook", func() {
 		}
 
 		doGetStructure.Times(8)
-		doGetFoudation.Times(0)
+		doGetFoudation.Times(8)
 		doGetJob.Times(0)
 		doFindJob.Times(0)
 		doGetStructureBluePrint.Times(8)
@@ -822,7 +967,7 @@ var _ = Describe("Structure Webhook", func() {
 
 		By("Call ValidateUpdate")
 		warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
-		Expect(warn).To(BeNil())
+		Expect(warn).To(Equal(admission.Warnings{"this will start a create job on host 'structure 123'"}))
 		Expect(err).To(BeNil())
 
 		structure.Status.Job = &contractorv1.JobStatus{}
@@ -889,6 +1034,16 @@ var _ = Describe("Structure Webhook", func() {
 	})
 
 	Context("When deleting strusture", func() {
+		BeforeEach(func() {
+			doGetStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(0)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+		})
+
 		It("Just fall through for now", func() {
 			By("ValidateDelete Setup")
 			structure := &contractorv1.Structure{
@@ -920,9 +1075,623 @@ var _ = Describe("Structure Webhook", func() {
 			Expect(structure.Spec.ConfigValues).To(HaveLen(0))
 			Expect(structure.Spec.State).To(Equal(""))
 		})
+		It("Should block deletion when protected, even if forced", func() {
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					contractorv1.DeletionProtectionAnnotation: "true",
+					contractorv1.ForceDeleteAnnotation:        "gone from contractor",
+				}},
+				Spec: contractorv1.StructureSpec{ID: 123, State: "planned"},
+			}
+
+			warn, err := validator.ValidateDelete(ctx, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(MatchError("deletion protection is enabled, remove the 'contractor.t3kton.com/deletion-protection' annotation to delete"))
+		})
+
+		It("Should require a reason to force delete", func() {
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{contractorv1.ForceDeleteAnnotation: " "}},
+				Spec:       contractorv1.StructureSpec{ID: 123, State: "built"},
+			}
+
+			warn, err := validator.ValidateDelete(ctx, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(MatchError("the 'contractor.t3kton.com/force-delete' annotation requires a reason"))
+		})
+
+		It("Should let through a forced delete", func() {
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{contractorv1.ForceDeleteAnnotation: "gone from contractor"}},
+				Spec:       contractorv1.StructureSpec{ID: 123, State: "built"},
+				Status:     contractorv1.StructureStatus{State: "built", Job: &contractorv1.JobStatus{}},
+			}
+
+			By("blocking without the annotation")
+			unforced := structure.DeepCopy()
+			unforced.Annotations = nil
+			_, err := validator.ValidateDelete(ctx, unforced)
+			Expect(err).To(HaveOccurred())
+
+			By("allowing with the annotation")
+			warn, err := validator.ValidateDelete(ctx, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(Equal(admission.Warnings{"force deleting, the structure on contractor is left as is: " +
+				"[can not delete Structure that is in built state, can not delete Structure that has a job]"}))
+		})
+
+		It("Should record who set the force delete annotation and add the finalizer", func() {
+			updateCtx := func(username string, old *contractorv1.Structure) context.Context {
+				oldRaw, err := json.Marshal(old)
+				Expect(err).NotTo(HaveOccurred())
+				return admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Update,
+					UserInfo:  authenticationv1.UserInfo{Username: username},
+					OldObject: runtime.RawExtension{Raw: oldRaw},
+				}})
+			}
+			oldStructure := &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 123, State: "built", BluePrint: "test-structure-base"}}
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{contractorv1.ForceDeleteAnnotation: "gone from contractor"}},
+				Spec:       contractorv1.StructureSpec{ID: 123, State: "built", BluePrint: "test-structure-base"},
+			}
+
+			Expect(defaulter.Default(updateCtx("alice", oldStructure), structure)).To(Succeed())
+			Expect(structure.Annotations).To(HaveKeyWithValue(contractorv1.ForceDeleteByAnnotation, "alice"))
+			Expect(structure.Finalizers).To(ConsistOf(contractorv1.ForceDeleteFinalizer))
+
+			By("keeping who set it while the reason is the same")
+			old := structure.DeepCopy()
+			structure.Annotations[contractorv1.ForceDeleteByAnnotation] = "bob"
+			Expect(defaulter.Default(updateCtx("bob", old), structure)).To(Succeed())
+			Expect(structure.Annotations).To(HaveKeyWithValue(contractorv1.ForceDeleteByAnnotation, "alice"))
+
+			By("recording who changed the reason")
+			old = structure.DeepCopy()
+			structure.Annotations[contractorv1.ForceDeleteAnnotation] = "replaced"
+			Expect(defaulter.Default(updateCtx("bob", old), structure)).To(Succeed())
+			Expect(structure.Annotations).To(HaveKeyWithValue(contractorv1.ForceDeleteByAnnotation, "bob"))
+
+			By("removing both when the annotation is removed")
+			old = structure.DeepCopy()
+			delete(structure.Annotations, contractorv1.ForceDeleteAnnotation)
+			Expect(defaulter.Default(updateCtx("bob", old), structure)).To(Succeed())
+			Expect(structure.Annotations).NotTo(HaveKey(contractorv1.ForceDeleteByAnnotation))
+			Expect(structure.Finalizers).To(BeEmpty())
+		})
+
+		It("Should allow setting the force delete annotation without contractor", func() {
+			oldStructure := &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 54321, BluePrint: "not-right"}}
+			structure := oldStructure.DeepCopy()
+			structure.Annotations = map[string]string{contractorv1.ForceDeleteAnnotation: "gone from contractor"}
+
+			warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("When a structure is bound to a consumer", func() {
+		It("Should only let consumer admins take it over", func() {
+			validator.ConsumerAdminGroups = []string{"platform-admins"}
+			oldStructure := &contractorv1.Structure{
+				Spec: contractorv1.StructureSpec{
+					ID:          123,
+					State:       "built",
+					BluePrint:   "test-structure-base",
+					ConsumerRef: &corev1.ObjectReference{Kind: "Machine", Name: "web01", UID: "1234"},
+				},
+				Status: contractorv1.StructureStatus{State: "built"},
+			}
+			structure := oldStructure.DeepCopy()
+			structure.Spec.ConsumerRef = &corev1.ObjectReference{Kind: "Machine", Name: "web02", UID: "5678"}
+
+			doGetStructure.Times(1)
+			doGetFoudation.Times(1)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(1)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			By("rejecting another team")
+			teamCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: "bob", Groups: []string{"team-b"}},
+			}})
+			warn, err := validator.ValidateUpdate(teamCtx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(MatchError(ContainSubstring("structure is bound to Machine 'web01', the consumerRef can only be changed by the same consumer")))
+
+			By("allowing a consumer admin")
+			adminCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: "alice", Groups: []string{"system:authenticated", "platform-admins"}},
+			}})
+			structure.Annotations = map[string]string{contractorv1.BoundByAnnotation: "alice"}
+			warn, err = validator.ValidateUpdate(adminCtx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(BeNil())
+		})
+
+		It("Should record who bound the structure", func() {
+			oldStructure := &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 123, State: "built", BluePrint: "test-structure-base"}}
+			oldRaw, err := json.Marshal(oldStructure)
+			Expect(err).NotTo(HaveOccurred())
+
+			doGetStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(0)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			bindCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				UserInfo:  authenticationv1.UserInfo{Username: "system:serviceaccount:machines:machine-controller"},
+				OldObject: runtime.RawExtension{Raw: oldRaw},
+			}})
+
+			By("recording the user that set the consumerRef")
+			structure := oldStructure.DeepCopy()
+			structure.Spec.ConsumerRef = &corev1.ObjectReference{Kind: "Machine", Name: "web01", UID: "1234"}
+			Expect(defaulter.Default(bindCtx, structure)).To(Succeed())
+			Expect(structure.Annotations).To(HaveKeyWithValue(contractorv1.BoundByAnnotation, "system:serviceaccount:machines:machine-controller"))
+
+			By("keeping who bound it when someone else tries to change it")
+			oldStructure = structure.DeepCopy()
+			oldRaw, err = json.Marshal(oldStructure)
+			Expect(err).NotTo(HaveOccurred())
+			otherCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+				UserInfo:  authenticationv1.UserInfo{Username: "bob"},
+				OldObject: runtime.RawExtension{Raw: oldRaw},
+			}})
+			structure.Annotations[contractorv1.BoundByAnnotation] = "bob"
+			Expect(defaulter.Default(otherCtx, structure)).To(Succeed())
+			Expect(structure.Annotations).To(HaveKeyWithValue(contractorv1.BoundByAnnotation, "system:serviceaccount:machines:machine-controller"))
+
+			By("not accepting consent from someone else")
+			structure.Annotations[contractorv1.ConsumerConsentAnnotation] = "1234"
+			structure.Spec.ConsumerRef = nil
+			_, err = validator.ValidateUpdate(otherCtx, oldStructure, structure)
+			Expect(err).To(MatchError(ContainSubstring("structure is bound to Machine 'web01', the consumerRef can only be changed by the same consumer")))
+		})
+	})
+
+	Context("When the request is a dry run", func() {
+		It("Should return the plan as warnings", func() {
+			validator.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "plan-test"}},
+				&contractorv1.ConfigProfile{
+					ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "plan-test"},
+					Spec:       contractorv1.ConfigProfileSpec{ConfigValues: contractorv1.ConfigValues{"ntp": contractorv1.NewConfigValue("pool.ntp.org")}},
+				},
+			).Build()
+			oldStructure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "plan-test"},
+				Spec: contractorv1.StructureSpec{
+					ID:           123,
+					State:        "planned",
+					BluePrint:    "test-structure-base",
+					ConfigValues: contractorv1.ConfigValues{"a": contractorv1.NewConfigValue("asdf")},
+				},
+				Status: contractorv1.StructureStatus{
+					State:        "planned",
+					BluePrint:    "test-structure-base",
+					Hostname:     "testing",
+					ConfigValues: contractorv1.ConfigValues{"a": contractorv1.NewConfigValue("asdf")},
+				},
+			}
+			structure := oldStructure.DeepCopy()
+			structure.Spec.State = "built"
+			structure.Spec.Profiles = []string{"base"}
+
+			doGetStructure.Times(1)
+			doGetFoudation.Times(1)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(1)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			dryRun := true
+			dryRunCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{DryRun: &dryRun}})
+			warn, err := validator.ValidateUpdate(dryRunCtx, oldStructure, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(Equal(admission.Warnings{
+				"plan: update the config values of 'testing': add 'ntp'",
+				"plan: start the 'create' job on 'testing'",
+			}))
+		})
+		It("Should plan a SensitiveKeys only change the same as the controller", func() {
+			oldStructure := &contractorv1.Structure{
+				Spec: contractorv1.StructureSpec{
+					ID:           123,
+					State:        "planned",
+					BluePrint:    "test-structure-base",
+					ConfigValues: contractorv1.ConfigValuesFromContractor(*mockStructure.ConfigValues),
+				},
+				Status: contractorv1.StructureStatus{
+					State:        "planned",
+					BluePrint:    "test-structure-base",
+					Hostname:     "testing",
+					ConfigValues: contractorv1.ConfigValuesFromContractor(*mockStructure.ConfigValues),
+				},
+			}
+			structure := oldStructure.DeepCopy()
+			structure.Spec.SensitiveKeys = []string{"a"}
+
+			doGetStructure.Times(2)
+			doGetFoudation.Times(1)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(1)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			dryRun := true
+			dryRunCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{DryRun: &dryRun}})
+			warn, err := validator.ValidateUpdate(dryRunCtx, oldStructure, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(Equal(admission.Warnings{"plan: no changes to contractor"}))
+		})
+	})
+
+	Context("When contractor is unavailable", func() {
+		BeforeEach(func() {
+			doGetStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(0)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			// open the circuit breaker with a failed connection
+			contractor.ConfigureLimits(contractor.LimitOptions{FailureThreshold: 1, OpenDuration: time.Hour})
+			Expect(contractor.SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+			mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:99:").Return(nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+			client, err := contractor.GetClient(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.BuildingStructureGet(ctx, 99)
+			Expect(err).To(HaveOccurred())
+			Expect(contractor.CircuitOpen()).To(BeTrue())
+
+			contractor.SetupTestingCache(map[int]*contractor.StructureState{123: {Structure: mockStructure, Foundation: mockFoundation}})
+			DeferCleanup(func() {
+				contractor.SetupTestingCache(nil)
+				contractor.ConfigureLimits(contractor.LimitOptions{})
+			})
+
+			validator.Degraded = DegradedOptions{Enabled: true, MaxCacheAge: time.Minute}
+			defaulter.Degraded = validator.Degraded
+		})
+
+		It("Should reject changes when degraded mode is not enabled", func() {
+			validator.Degraded.Enabled = false
+			oldStructure := &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 123, State: "planned", BluePrint: "test-structure-base"}}
+			structure := oldStructure.DeepCopy()
+			structure.Labels = map[string]string{"a": "b"}
+
+			warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(MatchError(contractor.ErrCircuitOpen))
+		})
+
+		It("Should report contractor as degraded while admitting changes, until contractor answers again", func() {
+			Expect(contractorDegraded()).To(Equal(1.0))
+
+			oldStructure := &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 54321, State: "planned", BluePrint: "not-right"}}
+			structure := oldStructure.DeepCopy()
+			structure.Labels = map[string]string{"a": "b"}
+			warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(HaveLen(1))
+			Expect(contractorDegraded()).To(Equal(1.0))
+
+			By("clearing once the circuit breaker lets a request through and it succeeds")
+			contractor.ConfigureLimits(contractor.LimitOptions{})
+			Expect(contractor.SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+			doGetStructure.Times(1)
+			doGetFoudation.Times(1)
+			doGetStructureBluePrint.Times(1)
+			oldStructure = &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 123, State: "planned", BluePrint: "test-structure-base"}}
+			warn, err = validator.ValidateUpdate(ctx, oldStructure, oldStructure.DeepCopy())
+			Expect(err).To(BeNil())
+			Expect(warn).To(BeNil())
+			Expect(contractorDegraded()).To(Equal(0.0))
+		})
+
+		It("Should admit changes that do not need contractor with a warning", func() {
+			oldStructure := &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 54321, State: "planned", BluePrint: "not-right"}}
+			structure := oldStructure.DeepCopy()
+			structure.Labels = map[string]string{"a": "b"}
+			structure.Spec.ConsumerRef = &corev1.ObjectReference{Kind: "Machine", Name: "web01"}
+
+			warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(Equal(admission.Warnings{"contractor is unavailable, circuit breaker is open, the change was admitted without checking contractor"}))
+		})
+
+		It("Should check changes that need contractor against the cache", func() {
+			oldStructure := &contractorv1.Structure{
+				Spec:   contractorv1.StructureSpec{ID: 123, State: "planned", BluePrint: "test-structure-base"},
+				Status: contractorv1.StructureStatus{State: "planned"},
+			}
+			structure := oldStructure.DeepCopy()
+			structure.Spec.State = "built"
+
+			warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(err).To(BeNil())
+			Expect(warn).To(Equal(admission.Warnings{
+				"contractor is unavailable, circuit breaker is open, the change was checked against a cached lookup of structure '123' and will be re-validated once contractor is available",
+				"this will start a create job on host 'structure 123'",
+			}))
+
+			By("still rejecting what can be checked without contractor")
+			structure.Spec.ConfigValues = contractorv1.ConfigValues{"bad name": contractorv1.NewConfigValue(1)}
+			_, err = validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(err).To(MatchError("invalid configuration value name 'bad name'"))
+
+			By("rejecting structures without a recent lookup")
+			structure = &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 124, State: "built", BluePrint: "test-structure-base"}}
+			_, err = validator.ValidateCreate(ctx, structure)
+			Expect(err).To(MatchError("contractor is unavailable, circuit breaker is open, and there is no lookup of structure '124' cached in the last 1m0s to validate against"))
+		})
+
+		It("Should mark the structure for the controller to re-validate and default from the cache", func() {
+			structure := &contractorv1.Structure{Spec: contractorv1.StructureSpec{ID: 123}}
+
+			Expect(defaulter.Default(ctx, structure)).To(Succeed())
+			Expect(structure.Annotations).To(HaveKey(contractorv1.UnvalidatedAnnotation))
+			Expect(structure.Spec.State).To(Equal("planned"))
+			Expect(structure.Spec.BluePrint).To(Equal("test-structure-base"))
+			Expect(structure.Spec.ConfigValues).To(HaveLen(3))
+
+			By("removing the mark once contractor is available")
+			contractor.ConfigureLimits(contractor.LimitOptions{})
+			Expect(contractor.SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+			Expect(defaulter.Default(ctx, structure)).To(Succeed())
+			Expect(structure.Annotations).NotTo(HaveKey(contractorv1.UnvalidatedAnnotation))
+		})
+	})
+
+	Context("When a StructurePolicy is bound to the namespace", func() {
+		var policy *contractorv1.StructurePolicy
+
+		BeforeEach(func() {
+			validator.Client = k8sClient
+			mockFoundation.Type = cinp.StringAddr("Manual")
+
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "policy-test", Labels: map[string]string{"team": "web"}}}
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, namespace))).To(Succeed())
+
+			policy = &contractorv1.StructurePolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "team-web"},
+				Spec: contractorv1.StructurePolicySpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+					BluePrints:        []string{"web-*"},
+					IDRanges:          []contractorv1.IDRange{{Min: 1, Max: 99}},
+					FoundationTypes:   []string{"VCenter"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+			})
+		})
+
+		It("Should reject structures that violate the policy, naming the policy", func() {
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "policy-test"},
+				Spec: contractorv1.StructureSpec{
+					ID:        123,
+					BluePrint: "test-structure-base",
+				},
+			}
+
+			doGetStructure.Times(3)
+			doGetFoudation.Times(2)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(1)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			By("Call ValidateCreate")
+			warn, err := validator.ValidateCreate(ctx, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("[StructurePolicy 'team-web': ID 123 is not in the allowed ranges [1-99], " +
+				"StructurePolicy 'team-web': blueprint 'test-structure-base' does not match any of [web-*], " +
+				"StructurePolicy 'team-web': foundation type 'Manual' is not one of [VCenter]]"))
+		})
+
+		It("Should not apply the policy to other namespaces", func() {
+			structure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: contractorv1.StructureSpec{
+					ID:        123,
+					BluePrint: "test-structure-base",
+				},
+			}
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))).To(Succeed())
+
+			doGetStructure.Times(2)
+			doGetFoudation.Times(1)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(1)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			By("Call ValidateCreate")
+			warn, err := validator.ValidateCreate(ctx, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("When a StructurePolicy restricts state transitions", func() {
+		BeforeEach(func() {
+			// a fake client, so the structures do not go through the webhooks
+			validator.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "transition-test"}},
+				&contractorv1.StructurePolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "build-only"},
+					Spec: contractorv1.StructurePolicySpec{
+						Namespaces:       []string{"transition-test"},
+						StateTransitions: []contractorv1.StateTransition{{From: "planned", To: "built"}},
+					},
+				},
+			).Build()
+		})
+
+		It("Should not be bypassed by clearing the state first", func() {
+			oldStructure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "transition-test"},
+				Spec: contractorv1.StructureSpec{
+					ID:        123,
+					State:     "built",
+					BluePrint: "test-structure-base",
+				},
+				Status: contractorv1.StructureStatus{State: "built"},
+			}
+			mockStructureState = "built"
+
+			doGetStructure.Times(2)
+			doGetFoudation.Times(2)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(2)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			By("Rejecting clearing the state")
+			structure := oldStructure.DeepCopy()
+			structure.Spec.State = ""
+			warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(MatchError("StructurePolicy 'build-only': changing the state from 'built' to '' is not permitted"))
+
+			By("Checking setting the state of a cleared structure against the state it is in")
+			oldStructure.Spec.State = ""
+			structure = oldStructure.DeepCopy()
+			structure.Spec.State = "planned"
+			warn, err = validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(MatchError("StructurePolicy 'build-only': changing the state from 'built' to 'planned' is not permitted"))
+		})
+	})
+
+	Context("When there is a StructureQuota in the namespace", func() {
+		BeforeEach(func() {
+			// a fake client, so the existing structure does not go through the webhooks
+			hard := int32(1)
+			validator.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "quota-test"}},
+				&contractorv1.StructureQuota{
+					ObjectMeta: metav1.ObjectMeta{Name: "team-quota", Namespace: "quota-test"},
+					Spec:       contractorv1.StructureQuotaSpec{Hard: &hard},
+				},
+				&contractorv1.Structure{
+					ObjectMeta: metav1.ObjectMeta{Name: "built", Namespace: "quota-test"},
+					Spec:       contractorv1.StructureSpec{ID: 124, State: "built", BluePrint: "test-structure-base"},
+				},
+			).Build()
+		})
+
+		It("Should reject building a structure that would exceed the quota", func() {
+			oldStructure := &contractorv1.Structure{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "quota-test"},
+				Spec: contractorv1.StructureSpec{
+					ID:        123,
+					State:     "planned",
+					BluePrint: "test-structure-base",
+				},
+				Status: contractorv1.StructureStatus{State: "planned"},
+			}
+			structure := oldStructure.DeepCopy()
+			structure.Spec.State = "built"
+
+			doGetStructure.Times(2)
+			doGetFoudation.Times(2)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(2)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+
+			By("Call ValidateUpdate")
+			warn, err := validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("exceeded StructureQuota 'team-quota': built structures, used 1 of 1"))
+
+			By("Allowing changes that do not build")
+			structure.Spec.State = "planned"
+			structure.Spec.ConfigValues = contractorv1.ConfigValues{"a": contractorv1.NewConfigValue(1)}
+			warn, err = validator.ValidateUpdate(ctx, oldStructure, structure)
+			Expect(warn).To(BeNil())
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("Tracing", func() {
+		var recorder *tracetest.SpanRecorder
+		var previous = otel.GetTracerProvider()
+
+		BeforeEach(func() {
+			recorder = tracetest.NewSpanRecorder()
+			tracing.SetProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+			doGetStructure.Times(0)
+			doGetFoudation.Times(0)
+			doGetJob.Times(0)
+			doFindJob.Times(0)
+			doGetStructureBluePrint.Times(0)
+			doGetInvalidStructure.Times(0)
+			doGetInvalidStructureBluePrint.Times(0)
+		})
+
+		AfterEach(func() {
+			otel.SetTracerProvider(previous)
+		})
+
+		It("Creates a span per admission call", func() {
+			parentCtx, parent := tracing.Start(ctx, "test")
+
+			Expect(defaulter.Default(parentCtx, &corev1.Pod{})).To(HaveOccurred())
+			_, err := validator.ValidateCreate(parentCtx, &corev1.Pod{})
+			Expect(err).To(HaveOccurred())
+			_, err = validator.ValidateUpdate(parentCtx, &corev1.Pod{}, &corev1.Pod{})
+			Expect(err).To(HaveOccurred())
+			parent.End()
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(4))
+			for i, name := range []string{"Structure.Default", "Structure.ValidateCreate", "Structure.ValidateUpdate"} {
+				Expect(spans[i].Name()).To(Equal(name))
+				Expect(spans[i].Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+				Expect(spans[i].Status().Code).To(Equal(codes.Error))
+			}
+		})
 	})
 })
 
 func TimeAddr(v time.Time) *time.Time {
 	return &v
 }
+
+// contractorDegraded returns the value of the contractor_degraded metric
+func contractorDegraded() float64 {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() == "contractor_degraded" {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	Fail("contractor_degraded metric not registered")
+	return 0
+}
@@ -0,0 +1,433 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	contractorClient "github.com/t3kton/contractor_goclient"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	structureJobURI = "/api/v1/Foreman/StructureJob"
+	// cacheChunkSize is how many objects are asked for per List/CallMulti request
+	cacheChunkSize = 50
+	// cacheIdleIntervals is how many refresh intervals a structure can go without being read before it is no longer refreshed
+	cacheIdleIntervals = 2
+	// cacheMaxAgeIntervals is how many refresh intervals old a cached state can be before reads fetch it from Contractor again
+	cacheMaxAgeIntervals = 3
+)
+
+// StructureState is what Contractor knows about a Structure, its Foundation, merged config and current job
+type StructureState struct {
+	Structure  *contractorClient.BuildingStructure
+	Foundation *contractorClient.BuildingFoundation
+	Config     map[string]interface{}
+	// Job is nil when the structure has no job
+	Job *contractorClient.ForemanStructureJob
+}
+
+type cacheEntry struct {
+	state    *StructureState
+	lastRead time.Time
+	// fetched is when the state was requested from Contractor
+	fetched time.Time
+	// failed is set when the last refresh could not get the state, reads fetch it from Contractor until it is stored again
+	failed bool
+}
+
+// stateCache holds the StructureState of the structures that have been asked for, the states are refreshed in bulk every interval
+type stateCache struct {
+	interval time.Duration
+	lock     sync.Mutex
+	entries  map[int]*cacheEntry
+	// invalidated is when a structure was last invalidated, so a refresh that started before a write does not store stale state
+	invalidated map[int]time.Time
+}
+
+var cache *stateCache = nil
+
+func structureURI(id int) string {
+	return "/api/v1/Building/Structure:" + strconv.Itoa(id) + ":"
+}
+
+// SetupCache enables the shared structure state cache, the returned Runnable refreshes it every interval and must be added to the manager
+func SetupCache(interval time.Duration) *CacheRefresher {
+	cache = &stateCache{interval: interval, entries: map[int]*cacheEntry{}, invalidated: map[int]time.Time{}}
+
+	return &CacheRefresher{}
+}
+
+// GetStructureState returns the state of the structure, from the cache if it is setup and the cached state is not too
+// old or marked failed by the last refresh, otherwise fetched from Contractor
+func GetStructureState(ctx context.Context, id int) (*StructureState, error) {
+	if cache == nil {
+		return fetchStructureState(ctx, id)
+	}
+
+	if state, ok := cache.get(id); ok {
+		return state, nil
+	}
+
+	start := time.Now()
+	state, err := fetchStructureState(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.lock.Lock()
+	cache.store(id, state, start)
+	cache.lock.Unlock()
+
+	return state, nil
+}
+
+// GetStructure returns the structure, from the cache if it is setup and has the state of the structure, otherwise only
+// the structure is fetched from Contractor
+func GetStructure(ctx context.Context, id int) (*contractorClient.BuildingStructure, error) {
+	if cache != nil {
+		if state, ok := cache.get(id); ok {
+			return state.Structure, nil
+		}
+	}
+
+	client, err := GetClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.BuildingStructureGet(ctx, id)
+}
+
+// CachedStructureState returns the cached state of the structure if it was fetched from Contractor within maxAge,
+// Contractor is never contacted, for use when Contractor is unavailable
+func CachedStructureState(id int, maxAge time.Duration) (*StructureState, bool) {
+	if cache == nil {
+		return nil, false
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	entry, ok := cache.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry.lastRead = time.Now()
+	if time.Since(entry.fetched) > maxAge {
+		return nil, false
+	}
+
+	return entry.state, true
+}
+
+// InvalidateStructure drops the cached state of the structure, call after making changes to the structure on Contractor
+func InvalidateStructure(id int) {
+	if cache == nil {
+		return
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	delete(cache.entries, id)
+	cache.invalidated[id] = time.Now()
+}
+
+// get returns the cached state if it is not too old or marked failed, and marks it as read
+func (c *stateCache) get(id int) (*StructureState, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry.lastRead = time.Now()
+	if entry.failed || time.Since(entry.fetched) > c.interval*cacheMaxAgeIntervals {
+		return nil, false
+	}
+
+	return entry.state, true
+}
+
+// store saves the state if the structure has not been invalidated since start, the lock must be held
+func (c *stateCache) store(id int, state *StructureState, start time.Time) {
+	if invalidated, ok := c.invalidated[id]; ok && !invalidated.Before(start) {
+		return
+	}
+
+	entry, ok := c.entries[id]
+	if !ok {
+		c.entries[id] = &cacheEntry{state: state, lastRead: time.Now(), fetched: start}
+		return
+	}
+	entry.state = state
+	entry.fetched = start
+	entry.failed = false
+}
+
+// fetchStructureState gets the state of a single structure from Contractor
+func fetchStructureState(ctx context.Context, id int) (*StructureState, error) {
+	logger := log.FromContext(ctx)
+
+	client, err := GetClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &StructureState{}
+
+	logger.Info("Getting Structure", "id", id)
+	state.Structure, err = client.BuildingStructureGet(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get structure faild: %w", err)
+	}
+
+	logger.Info("Getting Foundation", "id", *state.Structure.Foundation)
+	state.Foundation, err = client.BuildingFoundationGetURI(ctx, *state.Structure.Foundation)
+	if err != nil {
+		return nil, fmt.Errorf("get foundation faild: %w", err)
+	}
+
+	logger.Info("Getting Effective Config", "structure", id)
+	state.Config, err = state.Structure.CallGetConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get config faild: %w", err)
+	}
+
+	logger.Info("Getting Job", "structure", id)
+	jobURI, err := state.Structure.CallGetJob(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get job faild: %w", err)
+	}
+
+	if jobURI != "" {
+		state.Job, err = client.ForemanStructureJobGetURI(ctx, jobURI)
+		if err != nil {
+			return nil, fmt.Errorf("get job faild: %w", err)
+		}
+	}
+
+	return state, nil
+}
+
+// refresh re-fetches the state of the structures that have been read recently.  Configs are fetched with CallMulti in
+// chunks and the jobs are listed once, CInP has no multi-get so structures and foundations are fetched one at a time,
+// each foundation only once per refresh.  The states that could be fetched are stored, the others are marked failed so
+// the next read fetches them from Contractor, the returned error is the joined errors of the structures that failed.
+func (c *stateCache) refresh(ctx context.Context) error {
+	start := time.Now()
+
+	c.lock.Lock()
+	ids := make([]int, 0, len(c.entries))
+	for id, entry := range c.entries {
+		if start.Sub(entry.lastRead) > c.interval*cacheIdleIntervals {
+			delete(c.entries, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	c.lock.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	client, err := GetClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	states := make(map[int]*StructureState, len(ids))
+	failed := []int{}
+
+	jobs, err := listStructureJobs(ctx)
+	if err != nil {
+		c.finishRefresh(start, states, ids)
+		return fmt.Errorf("list structure jobs faild: %w", err)
+	}
+
+	configs, errs := getConfigs(ctx, ids)
+
+	foundations := map[string]*contractorClient.BuildingFoundation{}
+	for _, id := range ids {
+		config, ok := configs[structureURI(id)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config for structure '%d' missing", id))
+			failed = append(failed, id)
+			continue
+		}
+
+		state, err := refreshStructureState(ctx, client, id, foundations)
+		if err != nil {
+			errs = append(errs, err)
+			failed = append(failed, id)
+			continue
+		}
+		state.Config = config
+		state.Job = jobs[structureURI(id)]
+
+		states[id] = state
+	}
+
+	c.finishRefresh(start, states, failed)
+
+	return errors.Join(errs...)
+}
+
+// finishRefresh stores the states of a refresh that started at start and marks the failed structures
+func (c *stateCache) finishRefresh(start time.Time, states map[int]*StructureState, failed []int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for id, state := range states {
+		if _, ok := c.entries[id]; ok { // not invalidated or dropped while we were working
+			c.store(id, state, start)
+		}
+	}
+	for _, id := range failed {
+		if entry, ok := c.entries[id]; ok {
+			entry.failed = true
+		}
+	}
+	for id, invalidated := range c.invalidated {
+		if invalidated.Before(start) {
+			delete(c.invalidated, id)
+		}
+	}
+}
+
+// refreshStructureState gets the structure and foundation of a structure for refresh, foundations are shared between
+// the structures of a refresh
+func refreshStructureState(ctx context.Context, client *contractorClient.Contractor, id int, foundations map[string]*contractorClient.BuildingFoundation) (*StructureState, error) {
+	structure, err := client.BuildingStructureGet(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get structure '%d' faild: %w", id, err)
+	}
+
+	foundation, ok := foundations[*structure.Foundation]
+	if !ok {
+		foundation, err = client.BuildingFoundationGetURI(ctx, *structure.Foundation)
+		if err != nil {
+			return nil, fmt.Errorf("get foundation '%s' faild: %w", *structure.Foundation, err)
+		}
+		foundations[*structure.Foundation] = foundation
+	}
+
+	return &StructureState{Structure: structure, Foundation: foundation}, nil
+}
+
+// listStructureJobs returns the active structure jobs by structure URI
+func listStructureJobs(ctx context.Context) (map[string]*contractorClient.ForemanStructureJob, error) {
+	result := map[string]*contractorClient.ForemanStructureJob{}
+
+	position := 0
+	total := 1
+	for position < total {
+		uriList, first, count, newTotal, err := factory.cinp.List(ctx, structureJobURI, "", nil, position, cacheChunkSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, uri := range uriList {
+			object, err := factory.cinp.Get(ctx, uri)
+			if err != nil {
+				return nil, err
+			}
+
+			job, ok := (*object).(*contractorClient.ForemanStructureJob)
+			if !ok {
+				return nil, fmt.Errorf("expected a StructureJob for '%s' got %T", uri, *object)
+			}
+			if job.Structure != nil {
+				result[*job.Structure] = job
+			}
+		}
+
+		if count == 0 {
+			break
+		}
+		position = first + count
+		total = newTotal
+	}
+
+	return result, nil
+}
+
+// getConfigs returns the merged config of the structures by structure URI, and the errors of the chunks that failed
+func getConfigs(ctx context.Context, ids []int) (map[string]map[string]interface{}, []error) {
+	result := make(map[string]map[string]interface{}, len(ids))
+	errs := []error{}
+
+	for start := 0; start < len(ids); start += cacheChunkSize {
+		chunk := ids[start:min(start+cacheChunkSize, len(ids))]
+		idList := make([]string, len(chunk))
+		for i, id := range chunk {
+			idList[i] = strconv.Itoa(id)
+		}
+
+		args := map[string]interface{}{}
+		configs, err := factory.cinp.CallMulti(ctx, "/api/v1/Building/Structure:"+strings.Join(idList, ":")+":(getConfig)", &args)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get configs faild: %w", err))
+			continue
+		}
+
+		for uri, config := range *configs {
+			result[uri] = config
+		}
+	}
+
+	return result, errs
+}
+
+// CacheRefresher refreshes the structure state cache in the background
+type CacheRefresher struct{}
+
+// Start implements manager.Runnable
+func (r *CacheRefresher) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("contractor").WithName("cache")
+
+	ticker := time.NewTicker(cache.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if factory == nil {
+			continue
+		}
+
+		if err := cache.refresh(ctx); err != nil {
+			logger.Error(err, "unable to refresh contractor cache")
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the webhooks read from the cache on every replica
+func (r *CacheRefresher) NeedLeaderElection() bool {
+	return false
+}
+
+// SetupTestingCache sets up the cache with states as if they were just fetched, for testing, nil disables the cache
+func SetupTestingCache(states map[int]*StructureState) {
+	if states == nil {
+		cache = nil
+		return
+	}
+
+	SetupCache(time.Minute)
+	for id, state := range states {
+		cache.entries[id] = &cacheEntry{state: state, lastRead: time.Now(), fetched: time.Now()}
+	}
+}
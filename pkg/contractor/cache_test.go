@@ -0,0 +1,247 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	contractorClient "github.com/t3kton/contractor_goclient"
+	"go.uber.org/mock/gomock"
+	"t3kton.com/pkg/contractor/test_contractor"
+)
+
+var _ = Describe("Structure State Cache", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockCINP       *test_contractor.MockCInPClient
+		mockStructure  *contractorClient.BuildingStructure
+		mockFoundation *contractorClient.BuildingFoundation
+		mockJob        *contractorClient.ForemanStructureJob
+	)
+
+	ctx := context.Background()
+
+	expectFetch := func(times int) {
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:42:").DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+			result := cinp.Object(mockStructure)
+			return &result, nil
+		}).Times(times)
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Foundation:test:").DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+			result := cinp.Object(mockFoundation)
+			return &result, nil
+		}).Times(times)
+		mockCINP.EXPECT().Call(gomock.Any(), "/api/v1/Building/Structure:42:(getConfig)", gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, _ *map[string]interface{}, result *map[string]interface{}) error {
+				*result = map[string]interface{}{"hostname": "testing"}
+				return nil
+			}).Times(times)
+		mockCINP.EXPECT().Call(gomock.Any(), "/api/v1/Building/Structure:42:(getJob)", gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, _ *map[string]interface{}, result *string) error {
+				*result = ""
+				return nil
+			}).Times(times)
+	}
+
+	expectJobList := func(jobs ...*contractorClient.ForemanStructureJob) {
+		uriList := []string{}
+		for _, job := range jobs {
+			uriList = append(uriList, job.GetURI())
+			mockCINP.EXPECT().Get(gomock.Any(), job.GetURI()).DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+				result := cinp.Object(job)
+				return &result, nil
+			})
+		}
+		mockCINP.EXPECT().List(gomock.Any(), structureJobURI, "", gomock.Any(), 0, cacheChunkSize).Return(uriList, 0, len(uriList), len(uriList), nil)
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
+		Expect(SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+
+		uri, err := cinp.NewURI("/api/v1/")
+		Expect(err).NotTo(HaveOccurred())
+		mockCINP.EXPECT().GetURI().Return(uri).AnyTimes()
+
+		client, err := GetClient(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		mockStructure = client.BuildingStructureNewWithID(42)
+		mockStructure.ID = cinp.IntAddr(42)
+		mockStructure.Foundation = cinp.StringAddr("/api/v1/Building/Foundation:test:")
+
+		mockFoundation = client.BuildingFoundationNewWithID("test")
+		mockFoundation.Locator = cinp.StringAddr("test")
+
+		mockJob = client.ForemanStructureJobNewWithID(37)
+		mockJob.Structure = cinp.StringAddr("/api/v1/Building/Structure:42:")
+	})
+
+	AfterEach(func() {
+		cache = nil
+		factory = nil
+	})
+
+	It("Fetches from contractor when the cache is not setup", func() {
+		expectFetch(2)
+
+		for range 2 {
+			state, err := GetStructureState(ctx, 42)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.Structure).To(Equal(mockStructure))
+			Expect(state.Foundation).To(Equal(mockFoundation))
+			Expect(state.Config).To(Equal(map[string]interface{}{"hostname": "testing"}))
+			Expect(state.Job).To(BeNil())
+		}
+	})
+
+	It("Serves reads from memory until invalidated", func() {
+		SetupCache(time.Minute)
+		expectFetch(1)
+
+		for range 2 {
+			state, err := GetStructureState(ctx, 42)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.Structure).To(Equal(mockStructure))
+		}
+
+		structure, err := GetStructure(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(structure).To(Equal(mockStructure))
+
+		By("fetching again after being invalidated")
+		expectFetch(1)
+		InvalidateStructure(42)
+		_, err = GetStructureState(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Refreshes the read structures in bulk", func() {
+		SetupCache(time.Minute)
+		expectFetch(1)
+		_, err := GetStructureState(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+
+		mockCINP.EXPECT().CallMulti(gomock.Any(), "/api/v1/Building/Structure:42:(getConfig)", gomock.Any()).
+			Return(&map[string]map[string]interface{}{"/api/v1/Building/Structure:42:": {"hostname": "updated"}}, nil)
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:42:").DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+			result := cinp.Object(mockStructure)
+			return &result, nil
+		})
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Foundation:test:").DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+			result := cinp.Object(mockFoundation)
+			return &result, nil
+		})
+		expectJobList(mockJob)
+
+		Expect(cache.refresh(ctx)).To(Succeed())
+
+		state, err := GetStructureState(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Config).To(Equal(map[string]interface{}{"hostname": "updated"}))
+		Expect(state.Job).To(Equal(mockJob))
+	})
+
+	It("Stores the states that refreshed and fetches the failed ones on read", func() {
+		SetupCache(time.Minute)
+		other := &StructureState{}
+		cache.entries[43] = &cacheEntry{state: other, lastRead: time.Now(), fetched: time.Now()}
+		expectFetch(1)
+		_, err := GetStructureState(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+
+		mockCINP.EXPECT().CallMulti(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&map[string]map[string]interface{}{"/api/v1/Building/Structure:42:": {"hostname": "updated"}}, nil)
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:42:").DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+			result := cinp.Object(mockStructure)
+			return &result, nil
+		})
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Foundation:test:").DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+			result := cinp.Object(mockFoundation)
+			return &result, nil
+		})
+		expectJobList()
+
+		err = cache.refresh(ctx)
+		Expect(err).To(MatchError(ContainSubstring("config for structure '43' missing")))
+
+		state, err := GetStructureState(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Config).To(Equal(map[string]interface{}{"hostname": "updated"}))
+
+		By("still having the failed state for degraded mode")
+		cached, ok := CachedStructureState(43, time.Minute)
+		Expect(ok).To(BeTrue())
+		Expect(cached).To(BeIdenticalTo(other))
+
+		By("fetching the failed state from contractor on read")
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:43:").Return(nil, fmt.Errorf("not found"))
+		_, err = GetStructureState(ctx, 43)
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+	})
+
+	It("Marks every state failed when the jobs can not be listed", func() {
+		SetupCache(time.Minute)
+		cache.store(42, &StructureState{}, time.Now())
+
+		mockCINP.EXPECT().List(gomock.Any(), structureJobURI, "", gomock.Any(), 0, cacheChunkSize).Return(nil, 0, 0, 0, fmt.Errorf("timeout"))
+		Expect(cache.refresh(ctx)).To(MatchError(ContainSubstring("list structure jobs faild: timeout")))
+		Expect(cache.entries[42].failed).To(BeTrue())
+	})
+
+	It("Only fetches the structure when it is not cached", func() {
+		SetupCache(time.Minute)
+		mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:42:").DoAndReturn(func(_ context.Context, _ string) (*cinp.Object, error) {
+			result := cinp.Object(mockStructure)
+			return &result, nil
+		})
+
+		structure, err := GetStructure(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(structure).To(Equal(mockStructure))
+	})
+
+	It("Fetches states older than the max age from contractor", func() {
+		SetupCache(time.Minute)
+		cache.store(42, &StructureState{}, time.Now().Add(-time.Minute*cacheMaxAgeIntervals*2))
+
+		expectFetch(1)
+		state, err := GetStructureState(ctx, 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Structure).To(Equal(mockStructure))
+	})
+
+	It("Does not store a refresh that started before an invalidation", func() {
+		SetupCache(time.Minute)
+		state := &StructureState{}
+
+		start := time.Now()
+		InvalidateStructure(42)
+		cache.store(42, state, start)
+		Expect(cache.entries).NotTo(HaveKey(42))
+
+		cache.store(42, state, time.Now())
+		Expect(cache.entries).To(HaveKey(42))
+	})
+
+	It("Only returns recently fetched states without contacting contractor", func() {
+		_, ok := CachedStructureState(42, time.Minute)
+		Expect(ok).To(BeFalse())
+
+		SetupCache(time.Minute)
+		state := &StructureState{}
+		_, ok = CachedStructureState(42, time.Minute)
+		Expect(ok).To(BeFalse())
+
+		cache.store(42, state, time.Now().Add(-time.Minute*2))
+		cached, ok := CachedStructureState(42, time.Minute*5)
+		Expect(ok).To(BeTrue())
+		Expect(cached).To(BeIdenticalTo(state))
+
+		_, ok = CachedStructureState(42, time.Minute)
+		Expect(ok).To(BeFalse())
+	})
+})
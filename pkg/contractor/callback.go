@@ -0,0 +1,126 @@
+package contractor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const (
+	// CallbackPath is where the CallbackHandler is served on the webhook server
+	CallbackPath = "/contractor-callback"
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the timestamp, a ".", and the request body, prefixed with "sha256="
+	SignatureHeader = "X-Contractor-Signature"
+	// TimestampHeader carries when the notification was signed, in seconds since the epoch
+	TimestampHeader = "X-Contractor-Timestamp"
+	// callbackMaxSkew is how far from now the timestamp can be, so captured notifications can not be replayed later
+	callbackMaxSkew = time.Minute * 5
+	// maxCallbackBody is the largest notification that will be read
+	maxCallbackBody = 64 * 1024
+	// callbackQueueTimeout is how long to wait for the controller to take the notification, the controller only
+	// runs on the leader, so the sender is told to try again rather than being held up
+	callbackQueueTimeout = time.Second * 5
+)
+
+// Notification is what Contractor, or a relay, POSTs to the callback endpoint when a structure or it's job changes
+type Notification struct {
+	Structure int `json:"structure"`
+	// Job is optional, the ID of the structure job that changed
+	Job int `json:"job,omitempty"`
+}
+
+// Sign returns the SignatureHeader value for body sent with the TimestampHeader value timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CallbackHandler accepts signed Notifications and sends the structure ID on to be reconciled
+type CallbackHandler struct {
+	secret []byte
+	events chan event.TypedGenericEvent[int]
+}
+
+// NewCallbackHandler creates a CallbackHandler that verifies Notifications are signed with secret
+func NewCallbackHandler(secret []byte) *CallbackHandler {
+	return &CallbackHandler{secret: secret, events: make(chan event.TypedGenericEvent[int])}
+}
+
+// Events returns the channel the IDs of the notified structures are sent on, for use with source.Channel
+func (h *CallbackHandler) Events() <-chan event.TypedGenericEvent[int] {
+	return h.events
+}
+
+// ServeHTTP implements http.Handler
+func (h *CallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := ctrl.Log.WithName("contractor").WithName("callback")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackBody+1))
+	if err != nil {
+		http.Error(w, "unable to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxCallbackBody {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	signature := r.Header.Get(SignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") || !hmac.Equal([]byte(signature), []byte(Sign(h.secret, timestamp, body))) {
+		logger.Info("rejecting notification with an invalid signature", "remote", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid timestamp", http.StatusUnauthorized)
+		return
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > callbackMaxSkew || skew < -callbackMaxSkew {
+		logger.Info("rejecting notification outside the timestamp window", "remote", r.RemoteAddr, "timestamp", timestamp)
+		http.Error(w, "timestamp outside the allowed window", http.StatusUnauthorized)
+		return
+	}
+
+	var notification Notification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		http.Error(w, "invalid notification", http.StatusBadRequest)
+		return
+	}
+	if notification.Structure < 1 {
+		http.Error(w, "structure not specified", http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("notified", "structure", notification.Structure, "job", notification.Job)
+	InvalidateStructure(notification.Structure)
+
+	select {
+	case h.events <- event.TypedGenericEvent[int]{Object: notification.Structure}:
+	case <-r.Context().Done():
+		return
+	case <-time.After(callbackQueueTimeout):
+		http.Error(w, "not accepting notifications", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
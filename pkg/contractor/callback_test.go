@@ -0,0 +1,89 @@
+package contractor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("Callback Handler", func() {
+	secret := []byte("sssh")
+
+	now := func() string {
+		return strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	post := func(handler http.Handler, body string, timestamp string, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, CallbackPath, strings.NewReader(body))
+		req.Header.Set(TimestampHeader, timestamp)
+		if signature != "" {
+			req.Header.Set(SignatureHeader, signature)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	It("Accepts signed notifications", func() {
+		handler := NewCallbackHandler(secret)
+		body := `{"structure": 42, "job": 37}`
+
+		var received event.TypedGenericEvent[int]
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			received = <-handler.Events()
+		}()
+
+		timestamp := now()
+		Expect(post(handler, body, timestamp, Sign(secret, timestamp, []byte(body))).Code).To(Equal(http.StatusAccepted))
+		Eventually(done).Should(BeClosed())
+		Expect(received.Object).To(Equal(42))
+	})
+
+	It("Rejects unsigned and badly signed notifications", func() {
+		handler := NewCallbackHandler(secret)
+		body := `{"structure": 42}`
+
+		timestamp := now()
+
+		Expect(post(handler, body, timestamp, "").Code).To(Equal(http.StatusUnauthorized))
+		Expect(post(handler, body, timestamp, Sign([]byte("wrong"), timestamp, []byte(body))).Code).To(Equal(http.StatusUnauthorized))
+		Expect(post(handler, body, timestamp, strings.TrimPrefix(Sign(secret, timestamp, []byte(body)), "sha256=")).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Rejects replayed and re-timestamped notifications", func() {
+		handler := NewCallbackHandler(secret)
+		body := `{"structure": 42}`
+
+		By("rejecting a notification signed outside the window")
+		old := strconv.FormatInt(time.Now().Add(-callbackMaxSkew*2).Unix(), 10)
+		Expect(post(handler, body, old, Sign(secret, old, []byte(body))).Code).To(Equal(http.StatusUnauthorized))
+		future := strconv.FormatInt(time.Now().Add(callbackMaxSkew*2).Unix(), 10)
+		Expect(post(handler, body, future, Sign(secret, future, []byte(body))).Code).To(Equal(http.StatusUnauthorized))
+
+		By("rejecting an old signature sent with a new timestamp")
+		Expect(post(handler, body, now(), Sign(secret, old, []byte(body))).Code).To(Equal(http.StatusUnauthorized))
+
+		By("rejecting a signature without a timestamp")
+		Expect(post(handler, body, "", Sign(secret, "", []byte(body))).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Rejects invalid notifications", func() {
+		handler := NewCallbackHandler(secret)
+
+		timestamp := now()
+		Expect(post(handler, `{"job": 37}`, timestamp, Sign(secret, timestamp, []byte(`{"job": 37}`))).Code).To(Equal(http.StatusBadRequest))
+		Expect(post(handler, `not json`, timestamp, Sign(secret, timestamp, []byte(`not json`))).Code).To(Equal(http.StatusBadRequest))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, CallbackPath, nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
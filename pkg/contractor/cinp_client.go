@@ -0,0 +1,488 @@
+package contractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cinp "github.com/cinp/go"
+)
+
+const (
+	cinpRootPath = "/api/v1/"
+	cinpTimeout  = time.Second * 30
+	httpTrue     = "True"
+)
+
+// cinpServerError is returned for HTTP 500 results, it unwraps to a cinp.ServerError so it is classified the same as
+// the errors from the CInP client, which can not be made with a message outside of the cinp package
+type cinpServerError struct {
+	msg   string
+	trace string
+}
+
+func (e *cinpServerError) Error() string {
+	if e.trace != "" {
+		return fmt.Sprintf("Server Error: '%s' at '%s'", e.msg, e.trace)
+	}
+	return fmt.Sprintf("Server Error: '%s'", e.msg)
+}
+
+func (e *cinpServerError) Unwrap() error { return &cinp.ServerError{} }
+
+// cinpInvalidRequest is returned for HTTP 400 results, it unwraps to a cinp.InvalidRequest
+type cinpInvalidRequest struct {
+	msg string
+}
+
+func (e *cinpInvalidRequest) Error() string { return fmt.Sprintf("Invalid Request: '%s'", e.msg) }
+
+func (e *cinpInvalidRequest) Unwrap() error { return &cinp.InvalidRequest{} }
+
+// httpClient is a CInP client for one Contractor host, it works the same as the client from cinp.NewCInP, except
+// the requests are made with it's own transport instead of http.DefaultTransport, so the Contractor TLS options do
+// not apply to the other HTTP clients in the process.
+type httpClient struct {
+	host   string
+	uri    *cinp.URI
+	client *http.Client
+	log    *slog.Logger
+
+	lock         sync.RWMutex
+	headers      map[string]string
+	typeRegistry map[string]reflect.Type
+}
+
+// newHTTPClient creates a CInP client for the Contractor host, ie: "https://contractor", using the transport
+func newHTTPClient(log *slog.Logger, host string, transport http.RoundTripper) (*httpClient, error) {
+	if !(strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://")) {
+		return nil, errors.New("host does not start with http(s)://")
+	}
+
+	if strings.HasSuffix(host, "/") {
+		return nil, errors.New("host name must not end with '/'")
+	}
+
+	uri, err := cinp.NewURI(cinpRootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("New client", "host", host)
+
+	return &httpClient{
+		host:         host,
+		uri:          uri,
+		client:       &http.Client{Transport: transport, Timeout: cinpTimeout},
+		log:          log,
+		headers:      map[string]string{},
+		typeRegistry: map[string]reflect.Type{},
+	}, nil
+}
+
+// SetHeader sets a request header
+func (c *httpClient) SetHeader(name string, value string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.log.Debug("Set Header", "name", name)
+	c.headers[name] = value
+}
+
+// ClearHeader clears a request header
+func (c *httpClient) ClearHeader(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.log.Debug("Clearing Header", "name", name)
+	delete(c.headers, name)
+}
+
+// GetURI returns the URI parser
+func (c *httpClient) GetURI() *cinp.URI {
+	return c.uri
+}
+
+// RegisterType registers the type to decode the objects of the model at uri into
+func (c *httpClient) RegisterType(uri string, objectType reflect.Type) {
+	if _, ok := reflect.New(objectType).Interface().(cinp.Object); !ok {
+		panic(fmt.Sprintf("%v does not implement Object", objectType))
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.typeRegistry[uri] = objectType
+}
+
+func (c *httpClient) newObject(uri string) cinp.Object {
+	if offset := strings.IndexByte(uri, ':'); offset != -1 {
+		uri = uri[:offset]
+	}
+
+	c.lock.RLock()
+	objectType, ok := c.typeRegistry[uri]
+	c.lock.RUnlock()
+	if !ok {
+		objectType = cinp.MappedObjectType
+	}
+
+	return reflect.New(objectType).Interface().(cinp.Object)
+}
+
+func (c *httpClient) request(ctx context.Context, verb string, uri string, dataIn interface{}, dataOut interface{}, headers map[string]string) (int, map[string]string, error) {
+	var body []byte
+	if dataIn != nil {
+		buffer := &bytes.Buffer{}
+		encoder := json.NewEncoder(buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(dataIn); err != nil {
+			return 0, nil, err
+		}
+		body = buffer.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, verb, c.host+uri, bytes.NewBuffer(body))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.lock.RLock()
+	for k, v := range c.headers { // this must go first so the semi-untrusted "user" dosen't mess with the important stuff
+		req.Header.Set(k, v)
+	}
+	c.lock.RUnlock()
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("User-Agent", "golang CInP client")
+	req.Header.Set("Accepts", "application/json")
+	req.Header.Set("Accept-Charset", "utf-8")
+	req.Header.Set("CInP-Version", "1.0")
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	c.log.Debug("result", slog.Int("code", res.StatusCode))
+
+	switch res.StatusCode {
+	case 401:
+		return 0, nil, &cinp.InvalidSession{}
+	case 403:
+		return 0, nil, &cinp.NotAuthorized{}
+	case 404:
+		return 0, nil, &cinp.NotFound{}
+	case 200, 201, 202:
+	case 400, 500:
+		return 0, nil, resultError(res)
+	default:
+		return 0, nil, fmt.Errorf("HTTP Code '%d' unhandled", res.StatusCode)
+	}
+
+	if dataOut != nil {
+		err = json.NewDecoder(res.Body).Decode(dataOut)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, nil, fmt.Errorf("unable to parse response '%s'", err)
+		}
+	}
+
+	resultHeaders := make(map[string]string)
+	for _, v := range []string{"Position", "Count", "Total", "Type", "Multi-Object", "Object-Id", "verb"} {
+		resultHeaders[v] = res.Header.Get(v)
+	}
+
+	return res.StatusCode, resultHeaders, nil
+}
+
+// resultError makes the error for a 400 or 500 result, the body might not be JSON
+func resultError(res *http.Response) error {
+	var resultData map[string]interface{}
+	err := json.NewDecoder(res.Body).Decode(&resultData)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unable to parse response '%s' with code '%d'", err, res.StatusCode)
+	}
+
+	message, hasMessage := resultData["message"].(string)
+	if res.StatusCode == 400 {
+		if hasMessage {
+			return &cinpInvalidRequest{message}
+		}
+		return &cinpInvalidRequest{fmt.Sprintf("%v", resultData)}
+	}
+
+	if hasMessage {
+		if trace, ok := resultData["trace"]; ok {
+			return &cinpServerError{message, fmt.Sprintf("%v", trace)}
+		}
+		return &cinpServerError{message, ""}
+	}
+	return &cinpServerError{fmt.Sprintf("%v", resultData), ""}
+}
+
+// Describe describes the namespace, model or action at the uri
+func (c *httpClient) Describe(ctx context.Context, uri string) (*cinp.Describe, string, error) {
+	result := &cinp.Describe{}
+	c.log.Info("DESCRIBE", "uri", uri)
+
+	code, headers, err := c.request(ctx, "DESCRIBE", uri, nil, result, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if code != 200 {
+		return nil, "", fmt.Errorf("unexpected HTTP Code '%d' for DESCRIBE", code)
+	}
+
+	return result, headers["Type"], nil
+}
+
+// List lists the uris of the objects
+func (c *httpClient) List(ctx context.Context, uri string, filterName string, filterValues map[string]interface{}, position int, count int) ([]string, int, int, int, error) {
+	result := []string{}
+	if position < 0 || count < 0 {
+		return nil, 0, 0, 0, fmt.Errorf("position and count must be greater than 0")
+	}
+
+	headers := map[string]string{"Position": strconv.Itoa(position), "Count": strconv.Itoa(count)}
+	if filterName != "" {
+		headers["Filter"] = filterName
+	}
+
+	c.log.Info("LIST", "uri", uri)
+
+	code, headers, err := c.request(ctx, "LIST", uri, &filterValues, &result, headers)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	if code != 200 {
+		return nil, 0, 0, 0, fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	var total int
+	if position, err = strconv.Atoi(headers["Position"]); err != nil {
+		return nil, 0, 0, 0, err
+	}
+	if count, err = strconv.Atoi(headers["Count"]); err != nil {
+		return nil, 0, 0, 0, err
+	}
+	if total, err = strconv.Atoi(headers["Total"]); err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	return result, position, count, total, nil
+}
+
+// ListIds lists the uris of the objects in chunks, the channel is closed when done or a request fails
+func (c *httpClient) ListIds(ctx context.Context, uri string, filterName string, filterValues map[string]interface{}, chunkSize int) <-chan string {
+	if chunkSize < 1 {
+		chunkSize = 50
+	}
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		position := 0
+		total := 1
+		for position < total {
+			items, first, count, newTotal, err := c.List(ctx, uri, filterName, filterValues, position, chunkSize)
+			if err != nil || count == 0 {
+				return
+			}
+			for _, v := range items {
+				ch <- v
+			}
+			position = first + count
+			total = newTotal
+		}
+	}()
+	return ch
+}
+
+// ListObjects lists the objects in chunks, the channel is closed when done or a request fails
+func (c *httpClient) ListObjects(ctx context.Context, uri string, objectType reflect.Type, filterName string, filterValues map[string]interface{}, chunkSize int) <-chan *cinp.Object {
+	ch := make(chan *cinp.Object)
+	go func() {
+		defer close(ch)
+		for item := range c.ListIds(ctx, uri, filterName, filterValues, chunkSize) {
+			object, err := c.Get(ctx, item)
+			if err != nil {
+				return
+			}
+			ch <- object
+		}
+	}()
+	return ch
+}
+
+// Get gets the object at the uri
+func (c *httpClient) Get(ctx context.Context, uri string) (*cinp.Object, error) {
+	c.log.Info("GET", "uri", uri)
+
+	var code int
+	var headers map[string]string
+	var err error
+	result := c.newObject(uri)
+	if mo, ok := result.(*cinp.MappedObject); ok {
+		code, headers, err = c.request(ctx, "GET", uri, nil, &mo.Data, nil)
+	} else {
+		code, headers, err = c.request(ctx, "GET", uri, nil, result, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 200 {
+		return nil, fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	if headers["Multi-Object"] == httpTrue {
+		return nil, fmt.Errorf("detected multi object")
+	}
+
+	result.SetURI(uri)
+
+	return &result, nil
+}
+
+// Create creates an object with the values of object
+func (c *httpClient) Create(ctx context.Context, uri string, object cinp.Object) (*cinp.Object, error) {
+	c.log.Info("CREATE", "uri", uri)
+
+	code, headers, err := c.request(ctx, "CREATE", uri, object, object, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 201 {
+		return nil, fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	_, _, _, ids, _, err := c.uri.Split(headers["Object-Id"])
+	if err != nil {
+		return nil, err
+	}
+
+	if ids != nil && len(ids) != 1 {
+		return nil, fmt.Errorf("Create did not create any/one object")
+	}
+
+	object.SetURI(headers["Object-Id"])
+
+	return &object, nil
+}
+
+// Update sends the values of the object to be updated, the values Contractor sends back are put in the object
+func (c *httpClient) Update(ctx context.Context, object cinp.Object) (*cinp.Object, error) {
+	c.log.Info("UPDATE", "object", object.GetURI())
+
+	code, headers, err := c.request(ctx, "UPDATE", object.GetURI(), object, object, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 200 {
+		return nil, fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	if headers["Multi-Object"] == httpTrue {
+		return nil, fmt.Errorf("detected multi object")
+	}
+
+	return &object, nil
+}
+
+// UpdateMulti updates the objects at the uri with the values
+func (c *httpClient) UpdateMulti(ctx context.Context, uri string, values *map[string]interface{}, result *map[string]cinp.Object) error {
+	c.log.Info("UPDATE(multi)", "uri", uri)
+
+	code, headers, err := c.request(ctx, "UPDATE", uri, values, result, map[string]string{"Multi-Object": httpTrue})
+	if err != nil {
+		return err
+	}
+
+	if code != 200 {
+		return fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	if headers["Multi-Object"] != httpTrue {
+		return fmt.Errorf("no multi result detected")
+	}
+
+	return nil
+}
+
+// Delete deletes the object
+func (c *httpClient) Delete(ctx context.Context, object cinp.Object) error {
+	return c.DeleteURI(ctx, object.GetURI())
+}
+
+// DeleteURI deletes the object(s) at the uri
+func (c *httpClient) DeleteURI(ctx context.Context, uri string) error {
+	c.log.Info("DELETE", "uri", uri)
+
+	code, _, err := c.request(ctx, "DELETE", uri, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if code != 200 {
+		return fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	return nil
+}
+
+// Call calls the action at the uri
+func (c *httpClient) Call(ctx context.Context, uri string, args *map[string]interface{}, result interface{}) error {
+	c.log.Info("CALL", "uri", uri)
+
+	code, headers, err := c.request(ctx, "CALL", uri, args, result, nil)
+	if err != nil {
+		return err
+	}
+
+	if code != 200 {
+		return fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	if headers["Multi-Object"] == httpTrue {
+		return fmt.Errorf("detected multi object")
+	}
+
+	return nil
+}
+
+// CallMulti calls the action on each of the objects at the uri, the results are by object uri
+func (c *httpClient) CallMulti(ctx context.Context, uri string, args *map[string]interface{}) (*map[string]map[string]interface{}, error) {
+	c.log.Info("CALL(multi)", "uri", uri)
+
+	result := map[string]map[string]interface{}{}
+	code, headers, err := c.request(ctx, "CALL", uri, args, &result, map[string]string{"Multi-Object": httpTrue})
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 200 {
+		return nil, fmt.Errorf("unexpected HTTP code '%d'", code)
+	}
+
+	if headers["Multi-Object"] != httpTrue {
+		return nil, fmt.Errorf("no multi result detected")
+	}
+
+	return &result, nil
+}
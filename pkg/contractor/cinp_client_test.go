@@ -0,0 +1,142 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	contractorClient "github.com/t3kton/contractor_goclient"
+)
+
+var _ = Describe("CInP Client", func() {
+	var (
+		server  *httptest.Server
+		client  *httpClient
+		handler http.HandlerFunc
+	)
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+
+		var err error
+		client, err = newHTTPClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, http.DefaultTransport)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Checks the host", func() {
+		_, err := newHTTPClient(slog.New(slog.NewTextHandler(io.Discard, nil)), "contractor", http.DefaultTransport)
+		Expect(err).To(MatchError("host does not start with http(s)://"))
+		_, err = newHTTPClient(slog.New(slog.NewTextHandler(io.Discard, nil)), "http://contractor/", http.DefaultTransport)
+		Expect(err).To(MatchError("host name must not end with '/'"))
+	})
+
+	It("Gets objects as their registered type and sends the headers", func() {
+		var verb, authID string
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			verb = r.Method
+			authID = r.Header.Get("Auth-Id")
+			_, _ = w.Write([]byte(`{"hostname": "web01", "state": "built"}`))
+		}
+		client.RegisterType("/api/v1/Building/Structure", reflect.TypeOf((*contractorClient.BuildingStructure)(nil)).Elem())
+		client.SetHeader("Auth-Id", "k8s")
+
+		object, err := client.Get(ctx, "/api/v1/Building/Structure:42:")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verb).To(Equal("GET"))
+		Expect(authID).To(Equal("k8s"))
+		structure, ok := (*object).(*contractorClient.BuildingStructure)
+		Expect(ok).To(BeTrue())
+		Expect(*structure.Hostname).To(Equal("web01"))
+		Expect(structure.GetURI()).To(Equal("/api/v1/Building/Structure:42:"))
+
+		By("getting unregistered models as mapped objects")
+		object, err = client.Get(ctx, "/api/v1/Site/Site:test:")
+		Expect(err).NotTo(HaveOccurred())
+		Expect((*object).(*cinp.MappedObject).Data).To(HaveKeyWithValue("hostname", "web01"))
+
+		By("not sending cleared headers")
+		client.ClearHeader("Auth-Id")
+		_, err = client.Get(ctx, "/api/v1/Site/Site:test:")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authID).To(BeEmpty())
+	})
+
+	It("Returns the same errors as the CInP client", func() {
+		code := 0
+		body := ""
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(body))
+		}
+
+		code = 401
+		Expect(client.Call(ctx, "/api/v1/Auth/User(whoami)", nil, nil)).To(MatchError(&cinp.InvalidSession{}))
+		code = 404
+		Expect(client.Call(ctx, "/api/v1/Auth/User(whoami)", nil, nil)).To(MatchError(&cinp.NotFound{}))
+
+		code, body = 400, `{"message": "bad value"}`
+		err := client.Call(ctx, "/api/v1/Auth/User(whoami)", nil, nil)
+		Expect(err).To(MatchError("Invalid Request: 'bad value'"))
+		var invalidRequest *cinp.InvalidRequest
+		Expect(errors.As(err, &invalidRequest)).To(BeTrue())
+
+		code, body = 500, `{"message": "boom", "trace": "here"}`
+		err = client.Call(ctx, "/api/v1/Auth/User(whoami)", nil, nil)
+		Expect(err).To(MatchError("Server Error: 'boom' at 'here'"))
+		var serverError *cinp.ServerError
+		Expect(errors.As(err, &serverError)).To(BeTrue())
+
+		code, body = 503, ""
+		err = client.Call(ctx, "/api/v1/Auth/User(whoami)", nil, nil)
+		Expect(err).To(MatchError("HTTP Code '503' unhandled"))
+		Expect(isUnavailable(err)).To(BeTrue())
+	})
+
+	It("Lists and calls on many objects", func() {
+		var multi string
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			multi = r.Header.Get("Multi-Object")
+			switch r.Method {
+			case "LIST":
+				w.Header().Set("Position", "0")
+				w.Header().Set("Count", "2")
+				w.Header().Set("Total", "2")
+				_, _ = w.Write([]byte(`["/api/v1/Building/Structure:1:", "/api/v1/Building/Structure:2:"]`))
+			case "CALL":
+				w.Header().Set("Multi-Object", multi)
+				_, _ = w.Write([]byte(`{"/api/v1/Building/Structure:1:": {"a": 1}, "/api/v1/Building/Structure:2:": {"a": 2}}`))
+			}
+		}
+
+		uris, position, count, total, err := client.List(ctx, "/api/v1/Building/Structure", "", nil, 0, 50)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(uris).To(HaveLen(2))
+		Expect([]int{position, count, total}).To(Equal([]int{0, 2, 2}))
+
+		ids := []string{}
+		for uri := range client.ListIds(ctx, "/api/v1/Building/Structure", "", nil, 50) {
+			ids = append(ids, uri)
+		}
+		Expect(ids).To(Equal(uris))
+
+		result, err := client.CallMulti(ctx, "/api/v1/Building/Structure:1:2:(getConfig)", &map[string]interface{}{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(multi).To(Equal("True"))
+		Expect(*result).To(HaveKeyWithValue("/api/v1/Building/Structure:2:", map[string]interface{}{"a": 2.0}))
+	})
+})
@@ -2,7 +2,11 @@ package contractor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	cinp "github.com/cinp/go"
@@ -13,66 +17,242 @@ import (
 
 const tokenLifeTime = time.Minute * 10
 
+// ErrNotSetup is returned by GetClient when the factory has not been setup
+var ErrNotSetup = errors.New("contractor client factory not setup")
+
 // clientFactory creates authencated Contractor Clients
+// lock protects the credentials, tokenExpires and the login/logout of the client, the client its self is safe to use concurrently
 type clientFactory struct {
+	lock         sync.Mutex
 	username     string
 	password     string
 	client       *contractorClient.Contractor
+	cinp         cinp.CInPClient
+	limits       *limitedClient
+	endpoints    *failoverClient
 	tokenExpires time.Time
+	// generation is incremented every time a new token is obtained, used to avoid logging in multiple times for the same invalid session
+	generation atomic.Uint64
 }
 
 var factory *clientFactory = nil
 
-// SetupFactory sets up and checks the connection and authencation information
-func SetupFactory(ctx context.Context, hostname string, username string, password string, proxy string) error {
+func (f *clientFactory) credentials() Credentials {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return Credentials{Username: f.username, Password: f.password}
+}
+
+// login gets a new token, the lock must be held
+func (f *clientFactory) login(ctx context.Context) error {
+	if err := f.client.Login(ctx, f.username, f.password); err != nil {
+		f.tokenExpires = time.Time{} // make sure the next GetClient tries again
+		loginFailureCounter.Inc()
+		setDegraded(true)
+		return err
+	}
+
+	if f.generation.Load() > 0 {
+		tokenRefreshCounter.Inc()
+	}
+
+	f.tokenExpires = time.Now().Add(tokenLifeTime)
+	f.generation.Add(1)
+
+	return nil
+}
+
+// renew logs out and back in if the token will have expired by before, the lock must be held
+func (f *clientFactory) renew(ctx context.Context, before time.Time) error {
+	if before.Before(f.tokenExpires) {
+		return nil
+	}
+
+	f.client.Logout(ctx)
+	if err := f.login(ctx); err != nil {
+		return fmt.Errorf("unable to authencate to contractor: %w", err)
+	}
+
+	return nil
+}
+
+// relogin is called by the sessionClient when contractor reports the session as invalid, if the session has
+// been replaced since the failed request was made, there is nothing to do
+func (f *clientFactory) relogin(ctx context.Context, generation uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.generation.Load() != generation {
+		return nil
+	}
+
+	if err := f.login(ctx); err != nil {
+		return fmt.Errorf("unable to authencate to contractor after session became invalid: %w", err)
+	}
+
+	return nil
+}
+
+func newFactory(client cinp.CInPClient, creds Credentials) *clientFactory {
+	f := &clientFactory{username: creds.Username, password: creds.Password}
+	if endpoints, ok := client.(*failoverClient); ok {
+		f.endpoints = endpoints
+	}
+	client = &metricsClient{CInPClient: client}
+	if limitOptions != nil {
+		f.limits = newLimitedClient(client, *limitOptions)
+		client = f.limits
+	}
+	f.cinp = newSessionClient(client, f.generation.Load, f.relogin)
+	// the client has no constructor that takes a CInP client, so the wrapped client is swapped in, the models it
+	// would have registered are registered by registerTypes
+	f.client = &contractorClient.Contractor{}
+	f.client.OverrideCINPClient(f.cinp)
+
+	return f
+}
+
+// SetupFactory sets up and checks the connection and authencation information, hostnames are the Contractor
+// endpoints in order of preference, requests fail over to the next endpoint when one can not be connected to
+func SetupFactory(ctx context.Context, hostnames []string, creds Credentials, proxy string) error {
+	if len(hostnames) == 0 {
+		return errors.New("no contractor hosts specified")
+	}
+
 	log := ctrl.Log.WithName("contractor")
 	sloger := slog.New(logr.ToSlogHandler(log))
 
-	client, err := contractorClient.NewContractor(ctx, sloger, hostname, proxy, username, password)
+	transport, err := newRoundTripper(proxy)
 	if err != nil {
 		return err
 	}
 
-	factory = &clientFactory{username: username, password: password}
-	factory.client = client
-	factory.tokenExpires = time.Now().Add(tokenLifeTime)
+	endpoints := make([]endpoint, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		client, err := newHTTPClient(sloger, hostname, transport)
+		if err != nil {
+			return err
+		}
+		endpoints = append(endpoints, endpoint{host: hostname, client: client})
+	}
+
+	client := newFailoverClient(endpoints)
+	registerTypes(client)
+
+	f := newFactory(client, creds)
+
+	APIVersion, err := f.client.GetAPIVersion(ctx, "/api/v1/")
+	if err != nil {
+		return err
+	}
+	if APIVersion != "1.0" {
+		return fmt.Errorf("API version mismatch.  Got '%s', expected '1.0'", APIVersion)
+	}
+
+	if err := f.login(ctx); err != nil {
+		return err
+	}
+
+	factory = f
+
+	return nil
+}
+
+// UpdateCredentials logs in with the new credentials, if the login works, the new credentials are used from then on
+func UpdateCredentials(ctx context.Context, creds Credentials) error {
+	if factory == nil {
+		return ErrNotSetup
+	}
+
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+
+	factory.client.Logout(ctx)
+	oldUsername, oldPassword := factory.username, factory.password
+	factory.username, factory.password = creds.Username, creds.Password
+	err := factory.login(ctx)
+	if err != nil {
+		// get back to a working session with the old credentials, if that fails the next GetClient will try again
+		factory.username, factory.password = oldUsername, oldPassword
+		_ = factory.login(ctx)
+		return err
+	}
 
 	return nil
 }
 
 // CleanupFactory cleans up the factory, logingout/cleaning up the auth token
 func CleanupFactory(ctx context.Context) {
-	if factory == nil || factory.client == nil {
+	if factory == nil {
 		return
 	}
 
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+
 	factory.client.Logout(ctx)
 }
 
-// GetClient returns a authencated Contractor client
-func GetClient(ctx context.Context) *contractorClient.Contractor {
-	if factory == nil || factory.client == nil {
-		panic("Contractor Client Factory Not Setup")
+// GetClient returns a authencated Contractor client, if the token has expired and can not be
+// renewed an error is returned
+func GetClient(ctx context.Context) (*contractorClient.Contractor, error) {
+	if factory == nil {
+		return nil, ErrNotSetup
+	}
+
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+
+	if err := factory.renew(ctx, time.Now()); err != nil {
+		return nil, err
 	}
-	if time.Now().Compare(factory.tokenExpires) == 1 {
-		factory.client.Logout(ctx)
-		err := factory.client.Login(ctx, factory.username, factory.password)
+
+	return factory.client, nil
+}
+
+// TokenRefresher renews the Contractor token in the background before it expires, so requests
+// do not have to wait for a login
+type TokenRefresher struct {
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable
+func (t *TokenRefresher) Start(ctx context.Context) error {
+	log := ctrl.Log.WithName("contractor").WithName("token")
+
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if factory == nil {
+			continue
+		}
+
+		// renew if the token would expire before the next tick
+		factory.lock.Lock()
+		err := factory.renew(ctx, time.Now().Add(t.Interval*2))
+		factory.lock.Unlock()
 		if err != nil {
-			panic("Unable to Authencated to Contractor, creds no longer work")
+			log.Error(err, "unable to refresh contractor token")
 		}
-		factory.tokenExpires = time.Now().Add(tokenLifeTime)
 	}
+}
 
-	return factory.client
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the webhooks need a token on every replica
+func (t *TokenRefresher) NeedLeaderElection() bool {
+	return false
 }
 
 // SetupTestingFactory sets up the factory for testing
 func SetupTestingFactory(ctx context.Context, cinp cinp.CInPClient) error {
-	client := &contractorClient.Contractor{}
-	client.OverrideCINPClient(cinp)
-
-	factory = &clientFactory{username: "", password: ""}
-	factory.client = client
+	factory = newFactory(cinp, Credentials{})
 	factory.tokenExpires = time.Now().Add(time.Hour * 24) // no set of tests should take longer than a day, right?
 
 	return nil
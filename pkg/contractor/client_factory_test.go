@@ -0,0 +1,120 @@
+package contractor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"time"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	contractorClient "github.com/t3kton/contractor_goclient"
+	"go.uber.org/mock/gomock"
+	"t3kton.com/pkg/contractor/test_contractor"
+)
+
+var _ = Describe("Client Factory", func() {
+	var (
+		mockCtrl *gomock.Controller
+		mockCINP *test_contractor.MockCInPClient
+	)
+
+	ctx := context.Background()
+
+	expectLogin := func(token string) *gomock.Call {
+		return mockCINP.EXPECT().Call(gomock.Any(), loginURI, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, _ *map[string]interface{}, result interface{}) error {
+				*result.(*string) = token
+				return nil
+			})
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
+		Expect(SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		factory = nil
+	})
+
+	It("Registers the same models as the contractor client", func() {
+		registered := map[string]uintptr{}
+		mockCINP.EXPECT().RegisterType(gomock.Any(), gomock.Any()).Do(func(uri string, objectType reflect.Type) {
+			registered[uri] = reflect.ValueOf(objectType).Pointer()
+		}).AnyTimes()
+		registerTypes(mockCINP)
+
+		// the client does not export what it registers, so read it out of the type registry of the CInP client it makes
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Type", "Namespace")
+			_, _ = w.Write([]byte(`{"api-version": "1.0"}`))
+		}))
+		defer server.Close()
+		client, err := contractorClient.NewContractorInt(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "")
+		Expect(err).NotTo(HaveOccurred())
+		registry := reflect.ValueOf(client).Elem().FieldByName("cinp").Elem().Elem().FieldByName("typeRegistry")
+		Expect(registry.Kind()).To(Equal(reflect.Map), "the CInP client's type registry has moved")
+
+		expected := map[string]uintptr{}
+		for _, uri := range registry.MapKeys() {
+			expected[uri.String()] = registry.MapIndex(uri).Elem().Pointer()
+		}
+		Expect(registered).To(Equal(expected), "registerTypes is out of date with the contractor client")
+	})
+
+	It("Returns an error when not setup", func() {
+		factory = nil
+		_, err := GetClient(ctx)
+		Expect(err).To(MatchError(ErrNotSetup))
+	})
+
+	It("Returns an error instead of panicing when the token can not be renewed", func() {
+		factory.tokenExpires = time.Now().Add(-time.Minute)
+
+		mockCINP.EXPECT().Call(gomock.Any(), logoutURI, gomock.Any(), gomock.Any()).Return(nil)
+		mockCINP.EXPECT().ClearHeader(gomock.Any()).Times(2)
+		mockCINP.EXPECT().Call(gomock.Any(), loginURI, gomock.Any(), gomock.Any()).Return(&cinp.NotAuthorized{})
+
+		_, err := GetClient(ctx)
+		Expect(err).To(MatchError(ContainSubstring("unable to authencate to contractor")))
+
+		By("trying again on the next call")
+		mockCINP.EXPECT().Call(gomock.Any(), logoutURI, gomock.Any(), gomock.Any()).Return(nil)
+		mockCINP.EXPECT().ClearHeader(gomock.Any()).Times(2)
+		expectLogin("token")
+		mockCINP.EXPECT().SetHeader(gomock.Any(), gomock.Any()).Times(2)
+
+		client, err := GetClient(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client).NotTo(BeNil())
+	})
+
+	It("Retries once after logging in again when the session is invalid", func() {
+		client, err := GetClient(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		gomock.InOrder(
+			mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:42:").Return(nil, &cinp.InvalidSession{}),
+			expectLogin("new-token"),
+			mockCINP.EXPECT().SetHeader("Auth-Id", gomock.Any()),
+			mockCINP.EXPECT().SetHeader("Auth-Token", "new-token"),
+			mockCINP.EXPECT().Get(gomock.Any(), "/api/v1/Building/Structure:42:").Return(nil, &cinp.InvalidSession{}),
+		)
+
+		_, err = client.BuildingStructureGet(ctx, 42)
+		Expect(err).To(MatchError(&cinp.InvalidSession{}))
+	})
+
+	It("Does not login again when the session was already replaced", func() {
+		generation := factory.generation.Load()
+		factory.generation.Add(1)
+
+		Expect(factory.relogin(ctx, generation)).To(Succeed())
+	})
+})
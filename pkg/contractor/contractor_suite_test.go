@@ -0,0 +1,13 @@
+package contractor
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestContractor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Contractor Client Suite")
+}
@@ -0,0 +1,152 @@
+package contractor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultUsername and DefaultPassword are the well known credentials the flags default to
+	DefaultUsername = "k8s"
+	DefaultPassword = "k8s"
+)
+
+// Credentials are used to login to Contractor
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// IsDefault returns true if these are the well known default credentials
+func (c Credentials) IsDefault() bool {
+	return c.Username == DefaultUsername && c.Password == DefaultPassword
+}
+
+// check returns an error if the username or password is empty, ie: the placeholder Secret has not been filled in
+func (c Credentials) check() error {
+	if c.Username == "" || c.Password == "" {
+		return fmt.Errorf("the contractor username and password must not be empty")
+	}
+
+	return nil
+}
+
+// CredentialSource provides the current Contractor credentials
+type CredentialSource interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials are credentials that never change, ie: from the command line
+type StaticCredentials Credentials
+
+// Credentials implements CredentialSource
+func (s StaticCredentials) Credentials(_ context.Context) (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// FileCredentials reads the credentials from the 'username' and 'password' files in Dir, ie: a mounted basic-auth Secret
+type FileCredentials struct {
+	Dir string
+}
+
+// Credentials implements CredentialSource
+func (f FileCredentials) Credentials(_ context.Context) (Credentials, error) {
+	username, err := os.ReadFile(filepath.Join(f.Dir, corev1.BasicAuthUsernameKey))
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	password, err := os.ReadFile(filepath.Join(f.Dir, corev1.BasicAuthPasswordKey))
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	creds := Credentials{Username: strings.TrimSpace(string(username)), Password: strings.TrimSpace(string(password))}
+	if err := creds.check(); err != nil {
+		return Credentials{}, fmt.Errorf("credentials in '%s': %w", f.Dir, err)
+	}
+
+	return creds, nil
+}
+
+// SecretCredentials reads the credentials from the 'username' and 'password' keys of a Secret
+type SecretCredentials struct {
+	Reader client.Reader
+	Name   types.NamespacedName
+}
+
+// Credentials implements CredentialSource
+func (s SecretCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	var secret corev1.Secret
+	if err := s.Reader.Get(ctx, s.Name, &secret); err != nil {
+		return Credentials{}, err
+	}
+
+	username, ok := secret.Data[corev1.BasicAuthUsernameKey]
+	if !ok {
+		return Credentials{}, fmt.Errorf("secret '%s' is missing '%s'", s.Name, corev1.BasicAuthUsernameKey)
+	}
+
+	password, ok := secret.Data[corev1.BasicAuthPasswordKey]
+	if !ok {
+		return Credentials{}, fmt.Errorf("secret '%s' is missing '%s'", s.Name, corev1.BasicAuthPasswordKey)
+	}
+
+	creds := Credentials{Username: string(username), Password: string(password)}
+	if err := creds.check(); err != nil {
+		return Credentials{}, fmt.Errorf("secret '%s': %w", s.Name, err)
+	}
+
+	return creds, nil
+}
+
+// CredentialsWatcher checks the CredentialSource every Interval and re-logs in the factory when the credentials change
+type CredentialsWatcher struct {
+	Source   CredentialSource
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable
+func (w *CredentialsWatcher) Start(ctx context.Context) error {
+	log := ctrl.Log.WithName("contractor").WithName("credentials")
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		creds, err := w.Source.Credentials(ctx)
+		if err != nil {
+			log.Error(err, "unable to read contractor credentials")
+			continue
+		}
+
+		if factory == nil || creds == factory.credentials() {
+			continue
+		}
+
+		log.Info("contractor credentials changed, logging in again", "username", creds.Username)
+		if err := UpdateCredentials(ctx, creds); err != nil {
+			log.Error(err, "unable to login to contractor with the new credentials, keeping the old credentials")
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the webhooks need working credentials on every replica
+func (w *CredentialsWatcher) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,77 @@
+package contractor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Credentials", func() {
+	ctx := context.Background()
+
+	It("Detects the default credentials", func() {
+		Expect(Credentials{Username: "k8s", Password: "k8s"}.IsDefault()).To(BeTrue())
+		Expect(Credentials{Username: "k8s", Password: "other"}.IsDefault()).To(BeFalse())
+	})
+
+	It("Reads from a mounted secret", func() {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "username"), []byte("bob\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "password"), []byte("hunter2\n"), 0600)).To(Succeed())
+
+		creds, err := FileCredentials{Dir: dir}.Credentials(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(creds).To(Equal(Credentials{Username: "bob", Password: "hunter2"}))
+
+		By("picking up changes")
+		Expect(os.WriteFile(filepath.Join(dir, "password"), []byte("hunter3"), 0600)).To(Succeed())
+		creds, err = FileCredentials{Dir: dir}.Credentials(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(creds).To(Equal(Credentials{Username: "bob", Password: "hunter3"}))
+
+		By("failing when a file is empty")
+		Expect(os.WriteFile(filepath.Join(dir, "password"), []byte("\n"), 0600)).To(Succeed())
+		_, err = FileCredentials{Dir: dir}.Credentials(ctx)
+		Expect(err).To(MatchError(ContainSubstring("must not be empty")))
+
+		By("failing when a file is missing")
+		Expect(os.Remove(filepath.Join(dir, "password"))).To(Succeed())
+		_, err = FileCredentials{Dir: dir}.Credentials(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Reads from a secret", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "system", Name: "creds"},
+			Data:       map[string][]byte{"username": []byte("bob"), "password": []byte("hunter2")},
+		}
+		reader := fake.NewClientBuilder().WithObjects(secret).Build()
+
+		creds, err := SecretCredentials{Reader: reader, Name: types.NamespacedName{Namespace: "system", Name: "creds"}}.Credentials(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(creds).To(Equal(Credentials{Username: "bob", Password: "hunter2"}))
+
+		By("failing when the secret is missing")
+		_, err = SecretCredentials{Reader: reader, Name: types.NamespacedName{Namespace: "system", Name: "other"}}.Credentials(ctx)
+		Expect(err).To(HaveOccurred())
+
+		By("failing when the password is empty")
+		secret.Data["password"] = []byte{}
+		Expect(reader.Update(ctx, secret)).To(Succeed())
+		_, err = SecretCredentials{Reader: reader, Name: types.NamespacedName{Namespace: "system", Name: "creds"}}.Credentials(ctx)
+		Expect(err).To(MatchError("secret 'system/creds': the contractor username and password must not be empty"))
+
+		By("failing when the password is missing")
+		delete(secret.Data, "password")
+		Expect(reader.Update(ctx, secret)).To(Succeed())
+		_, err = SecretCredentials{Reader: reader, Name: types.NamespacedName{Namespace: "system", Name: "creds"}}.Credentials(ctx)
+		Expect(err).To(MatchError("secret 'system/creds' is missing 'password'"))
+	})
+})
@@ -0,0 +1,334 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	cinp "github.com/cinp/go"
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	activeEndpointGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "contractor_active_endpoint",
+		Help: "1 for the Contractor endpoint requests are currently sent to, 0 for the others",
+	}, []string{"endpoint"})
+
+	failoverCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "contractor_failovers_total",
+		Help: "Number of times requests were moved to a different Contractor endpoint",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(activeEndpointGauge, failoverCounter)
+}
+
+// endpoint is a CInP client for one Contractor host
+type endpoint struct {
+	host   string
+	client cinp.CInPClient
+}
+
+// failoverClient sends requests to the first endpoint that is working, when a request fails to connect, the next
+// endpoint is tried.  The auth headers are set on every endpoint, if the new endpoint does not accept the session
+// the sessionClient will login again.  Calls that start a job might have been done before the connection failed,
+// so getJob is checked on the new endpoint first, and if a job is found, it's ID is returned instead of starting another.
+// Like sessionClient, the iterators from ListIds and ListObjects are not failed over.
+type failoverClient struct {
+	endpoints []endpoint
+	lock      sync.RWMutex
+	active    int
+}
+
+func newFailoverClient(endpoints []endpoint) *failoverClient {
+	result := &failoverClient{endpoints: endpoints}
+	result.setActive(0)
+
+	return result
+}
+
+// setActive changes the active endpoint, the lock must be held
+func (f *failoverClient) setActive(index int) {
+	f.active = index
+	for i, item := range f.endpoints {
+		if i == index {
+			activeEndpointGauge.WithLabelValues(item.host).Set(1)
+		} else {
+			activeEndpointGauge.WithLabelValues(item.host).Set(0)
+		}
+	}
+}
+
+func (f *failoverClient) current() (int, cinp.CInPClient) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.active, f.endpoints[f.active].client
+}
+
+// failover moves off the failed endpoint, if another request has not already done so
+func (f *failoverClient) failover(failed int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.active != failed {
+		return
+	}
+
+	next := (failed + 1) % len(f.endpoints)
+	ctrl.Log.WithName("contractor").Info("failing over", "from", f.endpoints[failed].host, "to", f.endpoints[next].host)
+	failoverCounter.Inc()
+	f.setActive(next)
+}
+
+// isConnectionError is true if the request did not get an answer from the endpoint
+func isConnectionError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var netError net.Error
+	return errors.As(err, &netError)
+}
+
+// do runs f against the active endpoint, trying each endpoint once, verify is used before retrying a call that
+// may have partly happened, if verify returns true, the result it set is used instead of retrying
+func (f *failoverClient) do(ctx context.Context, fn func(client cinp.CInPClient) error, verify func(client cinp.CInPClient) (bool, error)) error {
+	var err error
+	for range f.endpoints {
+		index, client := f.current()
+		if err != nil && verify != nil {
+			done, verr := verify(client)
+			if verr != nil {
+				return err // can not tell if it happened, leave it to the caller to look before trying again
+			}
+			if done {
+				return nil
+			}
+		}
+
+		err = fn(client)
+		if !isConnectionError(ctx, err) {
+			return err
+		}
+
+		f.failover(index)
+	}
+
+	return err
+}
+
+// check moves back to the most preferred endpoint that answers
+func (f *failoverClient) check(ctx context.Context) {
+	active, _ := f.current()
+	for i := 0; i < active; i++ {
+		if _, _, err := f.endpoints[i].client.Describe(ctx, "/api/v1/"); err != nil {
+			continue
+		}
+
+		f.lock.Lock()
+		if f.active == active {
+			ctrl.Log.WithName("contractor").Info("failing back", "from", f.endpoints[active].host, "to", f.endpoints[i].host)
+			failoverCounter.Inc()
+			f.setActive(i)
+		}
+		f.lock.Unlock()
+		return
+	}
+}
+
+// SetHeader implements cinp.CInPClient
+func (f *failoverClient) SetHeader(name string, value string) {
+	for _, item := range f.endpoints {
+		item.client.SetHeader(name, value)
+	}
+}
+
+// ClearHeader implements cinp.CInPClient
+func (f *failoverClient) ClearHeader(name string) {
+	for _, item := range f.endpoints {
+		item.client.ClearHeader(name)
+	}
+}
+
+// RegisterType implements cinp.CInPClient
+func (f *failoverClient) RegisterType(uri string, objectType reflect.Type) {
+	for _, item := range f.endpoints {
+		item.client.RegisterType(uri, objectType)
+	}
+}
+
+// GetURI implements cinp.CInPClient
+func (f *failoverClient) GetURI() *cinp.URI {
+	_, client := f.current()
+	return client.GetURI()
+}
+
+// ListIds implements cinp.CInPClient
+func (f *failoverClient) ListIds(ctx context.Context, uri string, filterName string, filterValues map[string]interface{}, chunkSize int) <-chan string {
+	_, client := f.current()
+	return client.ListIds(ctx, uri, filterName, filterValues, chunkSize)
+}
+
+// ListObjects implements cinp.CInPClient
+func (f *failoverClient) ListObjects(ctx context.Context, uri string, objectType reflect.Type, filterName string, filterValues map[string]interface{}, chunkSize int) <-chan *cinp.Object {
+	_, client := f.current()
+	return client.ListObjects(ctx, uri, objectType, filterName, filterValues, chunkSize)
+}
+
+// Describe implements cinp.CInPClient
+func (f *failoverClient) Describe(ctx context.Context, uri string) (result *cinp.Describe, resultType string, err error) {
+	err = f.do(ctx, func(client cinp.CInPClient) (err error) {
+		result, resultType, err = client.Describe(ctx, uri)
+		return err
+	}, nil)
+	return
+}
+
+// List implements cinp.CInPClient
+func (f *failoverClient) List(ctx context.Context, uri string, filterName string, filterValues map[string]interface{}, position int, count int) (result []string, first int, last int, total int, err error) {
+	err = f.do(ctx, func(client cinp.CInPClient) (err error) {
+		result, first, last, total, err = client.List(ctx, uri, filterName, filterValues, position, count)
+		return err
+	}, nil)
+	return
+}
+
+// Get implements cinp.CInPClient
+func (f *failoverClient) Get(ctx context.Context, uri string) (result *cinp.Object, err error) {
+	err = f.do(ctx, func(client cinp.CInPClient) (err error) {
+		result, err = client.Get(ctx, uri)
+		return err
+	}, nil)
+	return
+}
+
+// Create implements cinp.CInPClient
+func (f *failoverClient) Create(ctx context.Context, uri string, object cinp.Object) (result *cinp.Object, err error) {
+	err = f.do(ctx, func(client cinp.CInPClient) (err error) {
+		result, err = client.Create(ctx, uri, object)
+		return err
+	}, nil)
+	return
+}
+
+// Update implements cinp.CInPClient
+func (f *failoverClient) Update(ctx context.Context, object cinp.Object) (result *cinp.Object, err error) {
+	err = f.do(ctx, func(client cinp.CInPClient) (err error) {
+		result, err = client.Update(ctx, object)
+		return err
+	}, nil)
+	return
+}
+
+// UpdateMulti implements cinp.CInPClient
+func (f *failoverClient) UpdateMulti(ctx context.Context, uri string, values *map[string]interface{}, result *map[string]cinp.Object) error {
+	return f.do(ctx, func(client cinp.CInPClient) error {
+		return client.UpdateMulti(ctx, uri, values, result)
+	}, nil)
+}
+
+// Delete implements cinp.CInPClient
+func (f *failoverClient) Delete(ctx context.Context, object cinp.Object) error {
+	return f.do(ctx, func(client cinp.CInPClient) error {
+		return client.Delete(ctx, object)
+	}, nil)
+}
+
+// DeleteURI implements cinp.CInPClient
+func (f *failoverClient) DeleteURI(ctx context.Context, uri string) error {
+	return f.do(ctx, func(client cinp.CInPClient) error {
+		return client.DeleteURI(ctx, uri)
+	}, nil)
+}
+
+// Call implements cinp.CInPClient
+func (f *failoverClient) Call(ctx context.Context, uri string, args *map[string]interface{}, result interface{}) error {
+	return f.do(ctx, func(client cinp.CInPClient) error {
+		return client.Call(ctx, uri, args, result)
+	}, jobStartVerifier(ctx, uri, result))
+}
+
+// CallMulti implements cinp.CInPClient
+func (f *failoverClient) CallMulti(ctx context.Context, uri string, args *map[string]interface{}) (result *map[string]map[string]interface{}, err error) {
+	err = f.do(ctx, func(client cinp.CInPClient) (err error) {
+		result, err = client.CallMulti(ctx, uri, args)
+		return err
+	}, nil)
+	return
+}
+
+// jobStartVerifier returns a verifier for the structure doCreate/doDestroy calls, that checks getJob for a job the
+// failed call may have started, nil for any other call
+func jobStartVerifier(ctx context.Context, uri string, result interface{}) func(client cinp.CInPClient) (bool, error) {
+	if !strings.HasPrefix(uri, "/api/v1/Building/Structure:") {
+		return nil
+	}
+
+	base, found := strings.CutSuffix(uri, "(doCreate)")
+	if !found {
+		base, found = strings.CutSuffix(uri, "(doDestroy)")
+	}
+	if !found {
+		return nil
+	}
+
+	return func(client cinp.CInPClient) (bool, error) {
+		jobURI := ""
+		if err := client.Call(ctx, base+"(getJob)", &map[string]interface{}{}, &jobURI); err != nil {
+			return false, err
+		}
+		if jobURI == "" {
+			return false, nil
+		}
+
+		id, ok := structureID(jobURI) // job URIs have the same shape
+		if !ok {
+			return false, fmt.Errorf("unable to parse job uri '%s'", jobURI)
+		}
+		if jobID, ok := result.(*int); ok {
+			*jobID = id
+		}
+
+		return true, nil
+	}
+}
+
+// EndpointMonitor periodically checks if a more preferred Contractor endpoint is answering again and moves back to it
+type EndpointMonitor struct {
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable
+func (m *EndpointMonitor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if factory == nil || factory.endpoints == nil {
+			continue
+		}
+
+		factory.endpoints.check(ctx)
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica makes requests to Contractor
+func (m *EndpointMonitor) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,120 @@
+package contractor
+
+import (
+	"context"
+	"net/url"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+	"t3kton.com/pkg/contractor/test_contractor"
+)
+
+var _ = Describe("Failover Client", func() {
+	var (
+		mockCtrl *gomock.Controller
+		primary  *test_contractor.MockCInPClient
+		backup   *test_contractor.MockCInPClient
+		failover *failoverClient
+	)
+
+	const uri = "/api/v1/Building/Structure:42:"
+
+	ctx := context.Background()
+	refused := &url.Error{Op: "Post", URL: "http://primary" + uri, Err: &connectionRefused{}}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		primary = test_contractor.NewMockCInPClient(mockCtrl)
+		backup = test_contractor.NewMockCInPClient(mockCtrl)
+		failover = newFailoverClient([]endpoint{{host: "http://primary", client: primary}, {host: "http://backup", client: backup}})
+	})
+
+	It("Fails over on connection errors and back when the primary answers", func() {
+		before := testutil.ToFloat64(failoverCounter)
+
+		By("sending headers to every endpoint")
+		primary.EXPECT().SetHeader("Auth-Token", "token")
+		backup.EXPECT().SetHeader("Auth-Token", "token")
+		failover.SetHeader("Auth-Token", "token")
+
+		By("not failing over for errors contractor answered with")
+		primary.EXPECT().Get(gomock.Any(), uri).Return(nil, &cinp.ServerError{})
+		_, err := failover.Get(ctx, uri)
+		Expect(err).To(MatchError(&cinp.ServerError{}))
+		Expect(failover.active).To(Equal(0))
+
+		By("retrying on the backup when the primary can not be connected to")
+		primary.EXPECT().Get(gomock.Any(), uri).Return(nil, refused)
+		backup.EXPECT().Get(gomock.Any(), uri).Return(nil, nil)
+		_, err = failover.Get(ctx, uri)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failover.active).To(Equal(1))
+		Expect(testutil.ToFloat64(activeEndpointGauge.WithLabelValues("http://primary"))).To(Equal(float64(0)))
+		Expect(testutil.ToFloat64(activeEndpointGauge.WithLabelValues("http://backup"))).To(Equal(float64(1)))
+
+		By("staying on the backup while the primary is down")
+		primary.EXPECT().Describe(gomock.Any(), "/api/v1/").Return(nil, "", refused)
+		failover.check(ctx)
+		Expect(failover.active).To(Equal(1))
+
+		By("failing back once the primary answers")
+		primary.EXPECT().Describe(gomock.Any(), "/api/v1/").Return(&cinp.Describe{}, "Namespace", nil)
+		failover.check(ctx)
+		Expect(failover.active).To(Equal(0))
+		Expect(testutil.ToFloat64(activeEndpointGauge.WithLabelValues("http://primary"))).To(Equal(float64(1)))
+		Expect(testutil.ToFloat64(failoverCounter) - before).To(Equal(float64(2)))
+	})
+
+	It("Returns the error when every endpoint fails", func() {
+		primary.EXPECT().Get(gomock.Any(), uri).Return(nil, refused)
+		backup.EXPECT().Get(gomock.Any(), uri).Return(nil, refused)
+		_, err := failover.Get(ctx, uri)
+		Expect(err).To(MatchError(refused))
+	})
+
+	It("Uses the job a failed doCreate started instead of starting another", func() {
+		primary.EXPECT().Call(gomock.Any(), uri+"(doCreate)", gomock.Any(), gomock.Any()).Return(refused)
+		backup.EXPECT().Call(gomock.Any(), uri+"(getJob)", gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, _ *map[string]interface{}, result interface{}) error {
+				*result.(*string) = "/api/v1/Foreman/StructureJob:12:"
+				return nil
+			})
+
+		jobID := 0
+		Expect(failover.Call(ctx, uri+"(doCreate)", &map[string]interface{}{}, &jobID)).To(Succeed())
+		Expect(jobID).To(Equal(12))
+	})
+
+	It("Retries doCreate when no job was started", func() {
+		primary.EXPECT().Call(gomock.Any(), uri+"(doCreate)", gomock.Any(), gomock.Any()).Return(refused)
+		backup.EXPECT().Call(gomock.Any(), uri+"(getJob)", gomock.Any(), gomock.Any()).Return(nil)
+		backup.EXPECT().Call(gomock.Any(), uri+"(doCreate)", gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, _ *map[string]interface{}, result interface{}) error {
+				*result.(*int) = 13
+				return nil
+			})
+
+		jobID := 0
+		Expect(failover.Call(ctx, uri+"(doCreate)", &map[string]interface{}{}, &jobID)).To(Succeed())
+		Expect(jobID).To(Equal(13))
+	})
+
+	It("Does not retry doCreate if it can not check for a job", func() {
+		primary.EXPECT().Call(gomock.Any(), uri+"(doCreate)", gomock.Any(), gomock.Any()).Return(refused)
+		backup.EXPECT().Call(gomock.Any(), uri+"(getJob)", gomock.Any(), gomock.Any()).Return(&cinp.ServerError{})
+
+		jobID := 0
+		err := failover.Call(ctx, uri+"(doCreate)", &map[string]interface{}{}, &jobID)
+		Expect(err).To(MatchError(refused))
+	})
+})
+
+// connectionRefused is a net.Error like the dialer returns when nothing is listening
+type connectionRefused struct{}
+
+func (e *connectionRefused) Error() string   { return "connection refused" }
+func (e *connectionRefused) Timeout() bool   { return false }
+func (e *connectionRefused) Temporary() bool { return false }
@@ -0,0 +1,95 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	defaultHealthTTL     = time.Second * 30
+	defaultHealthTimeout = time.Second * 10
+)
+
+var degradedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "contractor_degraded",
+	Help: "1 when the last check or request found Contractor unreachable, the circuit breaker open or the operator unable to authenticate, 0 otherwise",
+})
+
+func init() {
+	metrics.Registry.MustRegister(degradedGauge)
+}
+
+// setDegraded is called with the outcome of the health check, requests, logins and circuit breaker changes
+func setDegraded(value bool) {
+	if value {
+		degradedGauge.Set(1)
+	} else {
+		degradedGauge.Set(0)
+	}
+}
+
+// HealthChecker checks that Contractor is reachable and the operator is authenticated by calling whoami,
+// the result is cached for TTL so probes do not add load to Contractor
+type HealthChecker struct {
+	// TTL is how long a result is reused, defaults to 30 seconds
+	TTL time.Duration
+	// Timeout is how long the whoami call has, defaults to 10 seconds
+	Timeout time.Duration
+
+	lock    sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// Check implements healthz.Checker, contractor_degraded is set from the result
+func (h *HealthChecker) Check(req *http.Request) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	ttl := h.TTL
+	if ttl == 0 {
+		ttl = defaultHealthTTL
+	}
+
+	if !h.checked.IsZero() && time.Since(h.checked) < ttl {
+		return h.err
+	}
+
+	h.err = h.check(req.Context())
+	h.checked = time.Now()
+	setDegraded(h.err != nil)
+
+	return h.err
+}
+
+func (h *HealthChecker) check(ctx context.Context) error {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client, err := GetClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	username, err := client.AuthUserCallWhoami(ctx)
+	if err != nil {
+		return fmt.Errorf("contractor whoami failed: %w", err)
+	}
+
+	if username == "" {
+		return errors.New("not authenticated to contractor")
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package contractor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+	"t3kton.com/pkg/contractor/test_contractor"
+)
+
+var _ = Describe("Degraded", func() {
+	var (
+		mockCtrl *gomock.Controller
+		mockCINP *test_contractor.MockCInPClient
+	)
+
+	const whoamiURI = "/api/v1/Auth/User(whoami)"
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
+		setDegraded(false)
+	})
+
+	AfterEach(func() {
+		factory = nil
+		limitOptions = nil
+		setDegraded(false)
+	})
+
+	It("Follows the outcome of requests", func() {
+		client := &metricsClient{CInPClient: mockCINP}
+
+		mockCINP.EXPECT().Call(gomock.Any(), whoamiURI, gomock.Any(), gomock.Any()).Return(&net.OpError{Op: "dial"})
+		Expect(client.Call(ctx, whoamiURI, nil, nil)).NotTo(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(1.0))
+
+		By("not clearing on an error from contractor its self")
+		mockCINP.EXPECT().Call(gomock.Any(), whoamiURI, gomock.Any(), gomock.Any()).Return(&cinp.NotFound{})
+		Expect(client.Call(ctx, whoamiURI, nil, nil)).NotTo(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(1.0))
+
+		By("clearing once a request succeeds")
+		mockCINP.EXPECT().Call(gomock.Any(), whoamiURI, gomock.Any(), gomock.Any()).Return(nil)
+		Expect(client.Call(ctx, whoamiURI, nil, nil)).To(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(0.0))
+	})
+
+	It("Is set when the circuit breaker opens", func() {
+		client := newLimitedClient(mockCINP, LimitOptions{FailureThreshold: 1, OpenDuration: time.Hour})
+
+		mockCINP.EXPECT().Call(gomock.Any(), whoamiURI, gomock.Any(), gomock.Any()).Return(&net.OpError{Op: "dial"})
+		Expect(client.Call(ctx, whoamiURI, nil, nil)).NotTo(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(1.0))
+	})
+
+	It("Is set when logging in fails", func() {
+		Expect(SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+
+		mockCINP.EXPECT().Call(gomock.Any(), loginURI, gomock.Any(), gomock.Any()).Return(&cinp.NotAuthorized{})
+		Expect(factory.login(ctx)).NotTo(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(1.0))
+	})
+})
+
+var _ = Describe("Health Checker", func() {
+	var (
+		mockCtrl *gomock.Controller
+		mockCINP *test_contractor.MockCInPClient
+		req      *http.Request
+	)
+
+	const whoamiURI = "/api/v1/Auth/User(whoami)"
+
+	ctx := context.Background()
+
+	expectWhoami := func(username string, err error) *gomock.Call {
+		return mockCINP.EXPECT().Call(gomock.Any(), whoamiURI, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, _ *map[string]interface{}, result interface{}) error {
+				*result.(*string) = username
+				return err
+			})
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
+		Expect(SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+		setDegraded(false)
+
+		var err error
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, "/readyz", nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		factory = nil
+		setDegraded(false)
+	})
+
+	It("Reports ready and caches the result", func() {
+		expectWhoami("k8s", nil).Times(1)
+
+		checker := &HealthChecker{TTL: time.Hour}
+		Expect(checker.Check(req)).To(Succeed())
+		Expect(checker.Check(req)).To(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(0.0))
+	})
+
+	It("Reports not ready when contractor is unavailable", func() {
+		expectWhoami("", &cinp.ServerError{}).Times(1)
+
+		checker := &HealthChecker{TTL: time.Hour}
+		Expect(checker.Check(req)).NotTo(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(1.0))
+
+		By("checking again once the ttl has passed")
+		expectWhoami("k8s", nil).Times(1)
+		checker.checked = time.Now().Add(-time.Hour * 2)
+		Expect(checker.Check(req)).To(Succeed())
+		Expect(testutil.ToFloat64(degradedGauge)).To(Equal(0.0))
+	})
+
+	It("Reports not ready when not authenticated", func() {
+		expectWhoami("", nil)
+
+		Expect((&HealthChecker{}).Check(req)).To(MatchError("not authenticated to contractor"))
+	})
+
+	It("Reports not ready when the factory is not setup", func() {
+		factory = nil
+
+		Expect((&HealthChecker{}).Check(req)).To(MatchError(ErrNotSetup))
+	})
+})
@@ -0,0 +1,152 @@
+package contractor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	contractorClient "github.com/t3kton/contractor_goclient"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// jobSnapshot is the part of a job that is reported in the Structure's status
+type jobSnapshot struct {
+	id       int
+	state    string
+	canStart string
+	status   string
+	message  string
+	updated  time.Time
+}
+
+func newJobSnapshot(job *contractorClient.ForemanStructureJob) jobSnapshot {
+	result := jobSnapshot{}
+	if job.ID != nil {
+		result.id = *job.ID
+	}
+	if job.State != nil {
+		result.state = *job.State
+	}
+	if job.CanStart != nil {
+		result.canStart = *job.CanStart
+	}
+	if job.Status != nil {
+		result.status = *job.Status
+	}
+	if job.Message != nil {
+		result.message = *job.Message
+	}
+	if job.Updated != nil {
+		result.updated = *job.Updated
+	}
+	return result
+}
+
+// structureID extracts the id from a structure URI, ie: "/api/v1/Building/Structure:42:"
+func structureID(uri string) (int, bool) {
+	parts := strings.Split(uri, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// JobPoller lists the active structure jobs every Interval and sends the ID of each structure who's job started,
+// progressed or finished, so only those structures need to be reconciled
+type JobPoller struct {
+	Interval time.Duration
+	events   chan event.TypedGenericEvent[int]
+	jobs     map[string]jobSnapshot
+}
+
+// NewJobPoller creates a JobPoller, it must be added to the manager to start polling
+func NewJobPoller(interval time.Duration) *JobPoller {
+	return &JobPoller{Interval: interval, events: make(chan event.TypedGenericEvent[int])}
+}
+
+// Events returns the channel the IDs of the structures with job changes are sent on, for use with source.Channel
+func (p *JobPoller) Events() <-chan event.TypedGenericEvent[int] {
+	return p.events
+}
+
+// poll lists the active jobs and returns the IDs of the structures who's jobs have changed since the last poll,
+// the first poll reports every structure with a job
+func (p *JobPoller) poll(ctx context.Context) ([]int, error) {
+	jobs, err := listStructureJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]jobSnapshot, len(jobs))
+	changed := []string{}
+	for uri, job := range jobs {
+		snapshot := newJobSnapshot(job)
+		current[uri] = snapshot
+		if previous, ok := p.jobs[uri]; !ok || previous != snapshot {
+			changed = append(changed, uri)
+		}
+	}
+	for uri := range p.jobs {
+		if _, ok := current[uri]; !ok {
+			changed = append(changed, uri)
+		}
+	}
+	p.jobs = current
+
+	result := make([]int, 0, len(changed))
+	for _, uri := range changed {
+		if id, ok := structureID(uri); ok {
+			result = append(result, id)
+		}
+	}
+
+	return result, nil
+}
+
+// Start implements manager.Runnable
+func (p *JobPoller) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("contractor").WithName("jobs")
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if factory == nil {
+			continue
+		}
+
+		changed, err := p.poll(ctx)
+		if err != nil {
+			logger.Error(err, "unable to poll contractor jobs")
+			continue
+		}
+
+		for _, id := range changed {
+			InvalidateStructure(id) // so the reconcile sees the job change
+			select {
+			case <-ctx.Done():
+				return nil
+			case p.events <- event.TypedGenericEvent[int]{Object: id}:
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader is reconciling
+func (p *JobPoller) NeedLeaderElection() bool {
+	return true
+}
@@ -0,0 +1,102 @@
+package contractor
+
+import (
+	"context"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	contractorClient "github.com/t3kton/contractor_goclient"
+	"go.uber.org/mock/gomock"
+	"t3kton.com/pkg/contractor/test_contractor"
+)
+
+var _ = Describe("Job Poller", func() {
+	var (
+		mockCtrl *gomock.Controller
+		mockCINP *test_contractor.MockCInPClient
+		jobs     map[string]*contractorClient.ForemanStructureJob
+	)
+
+	ctx := context.Background()
+
+	newJob := func(id int, structure string, state string) *contractorClient.ForemanStructureJob {
+		job := &contractorClient.ForemanStructureJob{}
+		job.SetURI("/api/v1/Foreman/StructureJob:" + structure + ":")
+		job.ID = cinp.IntAddr(id)
+		job.Structure = cinp.StringAddr("/api/v1/Building/Structure:" + structure + ":")
+		job.State = cinp.StringAddr(state)
+		return job
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
+		Expect(SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+
+		jobs = map[string]*contractorClient.ForemanStructureJob{}
+		mockCINP.EXPECT().List(gomock.Any(), structureJobURI, "", gomock.Any(), 0, cacheChunkSize).DoAndReturn(
+			func(_ context.Context, _ string, _ string, _ map[string]interface{}, _ int, _ int) ([]string, int, int, int, error) {
+				uriList := []string{}
+				for uri := range jobs {
+					uriList = append(uriList, uri)
+				}
+				return uriList, 0, len(uriList), len(uriList), nil
+			}).AnyTimes()
+		mockCINP.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, uri string) (*cinp.Object, error) {
+			result := cinp.Object(jobs[uri])
+			return &result, nil
+		}).AnyTimes()
+	})
+
+	AfterEach(func() {
+		factory = nil
+	})
+
+	It("Reports only the structures who's jobs changed", func() {
+		poller := NewJobPoller(0)
+
+		By("reporting every job on the first poll")
+		jobs["/api/v1/Foreman/StructureJob:1:"] = newJob(10, "1", "waiting")
+		jobs["/api/v1/Foreman/StructureJob:2:"] = newJob(11, "2", "queued")
+		changed, err := poller.poll(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(ConsistOf(1, 2))
+
+		By("reporting nothing when nothing changed")
+		changed, err = poller.poll(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeEmpty())
+
+		By("reporting started, progressed and finished jobs")
+		jobs["/api/v1/Foreman/StructureJob:1:"] = newJob(10, "1", "queued")
+		delete(jobs, "/api/v1/Foreman/StructureJob:2:")
+		jobs["/api/v1/Foreman/StructureJob:3:"] = newJob(12, "3", "waiting")
+		changed, err = poller.poll(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(ConsistOf(1, 2, 3))
+	})
+
+	It("Reports jobs that can start", func() {
+		poller := NewJobPoller(0)
+
+		jobs["/api/v1/Foreman/StructureJob:1:"] = newJob(10, "1", "queued")
+		jobs["/api/v1/Foreman/StructureJob:1:"].CanStart = cinp.StringAddr("false")
+		_, err := poller.poll(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		jobs["/api/v1/Foreman/StructureJob:1:"].CanStart = cinp.StringAddr("true")
+		changed, err := poller.poll(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(ConsistOf(1))
+	})
+
+	It("Extracts the structure id from the uri", func() {
+		id, ok := structureID("/api/v1/Building/Structure:42:")
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal(42))
+
+		_, ok = structureID("/api/v1/Building/Structure")
+		Expect(ok).To(BeFalse())
+	})
+})
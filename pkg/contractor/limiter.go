@@ -0,0 +1,299 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"sync"
+	"time"
+
+	cinp "github.com/cinp/go"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ErrCircuitOpen is returned, without calling Contractor, while the circuit breaker is open
+var ErrCircuitOpen = errors.New("contractor is unavailable, circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+var breakerGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "contractor_circuit_breaker_state",
+	Help: "State of the Contractor circuit breaker, 0 closed, 1 half-open, 2 open",
+})
+
+func init() {
+	metrics.Registry.MustRegister(breakerGauge)
+}
+
+// LimitOptions configures the client side rate limit and circuit breaker for calls to Contractor
+type LimitOptions struct {
+	// QPS and Burst are the token bucket rate limit, a QPS of 0 disables the rate limit
+	QPS   float64
+	Burst int
+	// FailureThreshold is the number of consecutive failures that opens the circuit breaker, 0 disables the breaker
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open before a trial call is let through
+	OpenDuration time.Duration
+}
+
+var limitOptions *LimitOptions = nil
+
+// ConfigureLimits sets the rate limit and circuit breaker options, call before SetupFactory
+func ConfigureLimits(opts LimitOptions) {
+	limitOptions = &opts
+}
+
+// limitedClient wraps a CInP client with a token bucket rate limit and a circuit breaker.  Only failures that
+// indicate Contractor its self is in trouble (connection errors, timeouts and gateway errors) count toward opening
+// the breaker.  Like sessionClient, the iterators from ListIds and ListObjects are not limited.
+type limitedClient struct {
+	cinp.CInPClient
+	opts    LimitOptions
+	limiter *rate.Limiter
+
+	lock     sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// generation is bumped each time the breaker opens, so results of calls started before then are ignored
+	generation uint64
+}
+
+func newLimitedClient(c cinp.CInPClient, opts LimitOptions) *limitedClient {
+	result := &limitedClient{CInPClient: c, opts: opts}
+	if opts.QPS > 0 {
+		result.limiter = rate.NewLimiter(rate.Limit(opts.QPS), max(opts.Burst, 1))
+	}
+	breakerGauge.Set(float64(breakerClosed))
+
+	return result
+}
+
+// circuitOpen is true if calls are being refused
+func (l *limitedClient) circuitOpen() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.state == breakerOpen && time.Since(l.openedAt) < l.opts.OpenDuration
+}
+
+// CircuitOpen returns true if calls to Contractor are currently being refused by the circuit breaker
+func CircuitOpen() bool {
+	if factory == nil || factory.limits == nil {
+		return false
+	}
+
+	return factory.limits.circuitOpen()
+}
+
+func (l *limitedClient) setState(state breakerState) {
+	l.state = state
+	breakerGauge.Set(float64(state))
+}
+
+// allow checks the breaker, while half-open only a single trial call is let through.  The returned generation and
+// trial are handed back to record with the result of the call
+func (l *limitedClient) allow() (generation uint64, trial bool, ok bool) {
+	if l.opts.FailureThreshold < 1 {
+		return 0, false, true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	switch l.state {
+	case breakerOpen:
+		if time.Since(l.openedAt) < l.opts.OpenDuration {
+			return 0, false, false
+		}
+		l.setState(breakerHalfOpen)
+		return l.generation, true, true
+	case breakerHalfOpen:
+		return 0, false, false // the trial call is in flight
+	}
+
+	return l.generation, false, true
+}
+
+// open opens the breaker, must be called with the lock held
+func (l *limitedClient) open() {
+	l.openedAt = time.Now()
+	l.generation++
+	l.setState(breakerOpen)
+	setDegraded(true)
+}
+
+// record updates the breaker with the result of a call.  Only the trial call closes the breaker, results of calls
+// started before the breaker last opened are ignored, and a canceled call says nothing about Contractor
+func (l *limitedClient) record(generation uint64, trial bool, err error) {
+	if l.opts.FailureThreshold < 1 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if generation != l.generation {
+		return
+	}
+
+	if errors.Is(err, context.Canceled) {
+		if trial { // give the next call the trial
+			l.setState(breakerOpen)
+		}
+		return
+	}
+
+	if trial {
+		if isUnavailable(err) {
+			l.open()
+			return
+		}
+		l.failures = 0
+		l.setState(breakerClosed)
+		return
+	}
+
+	if l.state != breakerClosed {
+		return
+	}
+
+	if !isUnavailable(err) {
+		l.failures = 0
+		return
+	}
+
+	l.failures++
+	if l.failures >= l.opts.FailureThreshold {
+		l.open()
+	}
+}
+
+// gatewayError matches the error CInP returns for a 502, 503 or 504 from a proxy or load balancer in front of
+// Contractor, CInP has no error type for them
+var gatewayError = regexp.MustCompile(`HTTP Code '50[234]' unhandled`)
+
+// isUnavailable is true for errors that mean Contractor could not answer, ie: transport errors, timeouts and gateway
+// errors, as opposed to Contractor answering with an error, including a 500 from a failed request
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netError net.Error
+	return errors.As(err, &netError) || errors.Is(err, context.DeadlineExceeded) || gatewayError.MatchString(err.Error())
+}
+
+// do runs f if the breaker and rate limit allow it
+func (l *limitedClient) do(ctx context.Context, f func() error) error {
+	if l.circuitOpen() { // fail fast, without waiting on the rate limit
+		return ErrCircuitOpen
+	}
+
+	if l.limiter != nil {
+		if err := l.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	generation, trial, ok := l.allow()
+	if !ok {
+		return ErrCircuitOpen
+	}
+
+	err := f()
+	l.record(generation, trial, err)
+
+	return err
+}
+
+// Describe implements cinp.CInPClient
+func (l *limitedClient) Describe(ctx context.Context, uri string) (result *cinp.Describe, resultType string, err error) {
+	err = l.do(ctx, func() (err error) {
+		result, resultType, err = l.CInPClient.Describe(ctx, uri)
+		return err
+	})
+	return
+}
+
+// List implements cinp.CInPClient
+func (l *limitedClient) List(ctx context.Context, uri string, filterName string, filterValues map[string]interface{}, position int, count int) (result []string, first int, last int, total int, err error) {
+	err = l.do(ctx, func() (err error) {
+		result, first, last, total, err = l.CInPClient.List(ctx, uri, filterName, filterValues, position, count)
+		return err
+	})
+	return
+}
+
+// Get implements cinp.CInPClient
+func (l *limitedClient) Get(ctx context.Context, uri string) (result *cinp.Object, err error) {
+	err = l.do(ctx, func() (err error) {
+		result, err = l.CInPClient.Get(ctx, uri)
+		return err
+	})
+	return
+}
+
+// Create implements cinp.CInPClient
+func (l *limitedClient) Create(ctx context.Context, uri string, object cinp.Object) (result *cinp.Object, err error) {
+	err = l.do(ctx, func() (err error) {
+		result, err = l.CInPClient.Create(ctx, uri, object)
+		return err
+	})
+	return
+}
+
+// Update implements cinp.CInPClient
+func (l *limitedClient) Update(ctx context.Context, object cinp.Object) (result *cinp.Object, err error) {
+	err = l.do(ctx, func() (err error) {
+		result, err = l.CInPClient.Update(ctx, object)
+		return err
+	})
+	return
+}
+
+// UpdateMulti implements cinp.CInPClient
+func (l *limitedClient) UpdateMulti(ctx context.Context, uri string, values *map[string]interface{}, result *map[string]cinp.Object) error {
+	return l.do(ctx, func() error {
+		return l.CInPClient.UpdateMulti(ctx, uri, values, result)
+	})
+}
+
+// Delete implements cinp.CInPClient
+func (l *limitedClient) Delete(ctx context.Context, object cinp.Object) error {
+	return l.do(ctx, func() error {
+		return l.CInPClient.Delete(ctx, object)
+	})
+}
+
+// DeleteURI implements cinp.CInPClient
+func (l *limitedClient) DeleteURI(ctx context.Context, uri string) error {
+	return l.do(ctx, func() error {
+		return l.CInPClient.DeleteURI(ctx, uri)
+	})
+}
+
+// Call implements cinp.CInPClient
+func (l *limitedClient) Call(ctx context.Context, uri string, args *map[string]interface{}, result interface{}) error {
+	return l.do(ctx, func() error {
+		return l.CInPClient.Call(ctx, uri, args, result)
+	})
+}
+
+// CallMulti implements cinp.CInPClient
+func (l *limitedClient) CallMulti(ctx context.Context, uri string, args *map[string]interface{}) (result *map[string]map[string]interface{}, err error) {
+	err = l.do(ctx, func() (err error) {
+		result, err = l.CInPClient.CallMulti(ctx, uri, args)
+		return err
+	})
+	return
+}
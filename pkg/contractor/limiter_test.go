@@ -0,0 +1,193 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+	"t3kton.com/pkg/contractor/test_contractor"
+)
+
+var _ = Describe("Limited Client", func() {
+	var (
+		mockCtrl *gomock.Controller
+		mockCINP *test_contractor.MockCInPClient
+	)
+
+	const uri = "/api/v1/Building/Structure:42:"
+
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockCINP = test_contractor.NewMockCInPClient(mockCtrl)
+	})
+
+	AfterEach(func() {
+		limitOptions = nil
+		factory = nil
+	})
+
+	It("Opens after consecutive failures and closes after a good trial call", func() {
+		limited := newLimitedClient(mockCINP, LimitOptions{FailureThreshold: 2, OpenDuration: time.Hour})
+
+		By("not counting errors contractor answered with")
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, &cinp.NotFound{}).Times(3)
+		for range 3 {
+			_, err := limited.Get(ctx, uri)
+			Expect(err).To(MatchError(&cinp.NotFound{}))
+		}
+
+		By("not counting a server error from a failed request")
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, &cinp.ServerError{}).Times(3)
+		for range 3 {
+			_, err := limited.Get(ctx, uri)
+			Expect(err).To(MatchError(&cinp.ServerError{}))
+		}
+		Expect(limited.circuitOpen()).To(BeFalse())
+
+		By("opening after the threshold")
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, errors.New("HTTP Code '503' unhandled"))
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, &net.OpError{Op: "dial"})
+		for range 2 {
+			_, err := limited.Get(ctx, uri)
+			Expect(err).To(HaveOccurred())
+		}
+		Expect(limited.circuitOpen()).To(BeTrue())
+		Expect(testutil.ToFloat64(breakerGauge)).To(Equal(float64(breakerOpen)))
+
+		By("refusing calls while open")
+		_, err := limited.Get(ctx, uri)
+		Expect(err).To(MatchError(ErrCircuitOpen))
+
+		By("letting a trial call through once the open duration has passed")
+		limited.openedAt = time.Now().Add(-time.Hour * 2)
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, nil)
+		_, err = limited.Get(ctx, uri)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limited.circuitOpen()).To(BeFalse())
+		Expect(testutil.ToFloat64(breakerGauge)).To(Equal(float64(breakerClosed)))
+	})
+
+	It("Re-opens when the trial call fails", func() {
+		limited := newLimitedClient(mockCINP, LimitOptions{FailureThreshold: 1, OpenDuration: time.Hour})
+
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, context.DeadlineExceeded).Times(2)
+		_, err := limited.Get(ctx, uri)
+		Expect(err).To(HaveOccurred())
+		Expect(limited.circuitOpen()).To(BeTrue())
+
+		limited.openedAt = time.Now().Add(-time.Hour * 2)
+		_, err = limited.Get(ctx, uri)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(limited.circuitOpen()).To(BeTrue())
+	})
+
+	It("Only closes after the trial call", func() {
+		limited := newLimitedClient(mockCINP, LimitOptions{FailureThreshold: 1, OpenDuration: time.Hour})
+
+		By("ignoring a success from a call started before the breaker opened")
+		generation, trial, ok := limited.allow()
+		Expect(ok).To(BeTrue())
+		Expect(trial).To(BeFalse())
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, &net.OpError{Op: "dial"})
+		_, err := limited.Get(ctx, uri)
+		Expect(err).To(HaveOccurred())
+		Expect(limited.circuitOpen()).To(BeTrue())
+		limited.record(generation, trial, nil)
+		Expect(limited.circuitOpen()).To(BeTrue())
+
+		By("ignoring a success from a call started before the breaker went half-open")
+		limited.openedAt = time.Now().Add(-time.Hour * 2)
+		generation, trial, ok = limited.allow()
+		Expect(ok).To(BeTrue())
+		Expect(trial).To(BeTrue())
+		limited.record(generation-1, false, nil)
+		Expect(limited.state).To(Equal(breakerHalfOpen))
+
+		By("closing when the trial call completes")
+		limited.record(generation, trial, &cinp.NotFound{})
+		Expect(limited.state).To(Equal(breakerClosed))
+		Expect(testutil.ToFloat64(breakerGauge)).To(Equal(float64(breakerClosed)))
+	})
+
+	It("Does not count canceled calls", func() {
+		limited := newLimitedClient(mockCINP, LimitOptions{FailureThreshold: 2, OpenDuration: time.Hour})
+
+		By("not resetting the failure count")
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, &net.OpError{Op: "dial"})
+		_, err := limited.Get(ctx, uri)
+		Expect(err).To(HaveOccurred())
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, fmt.Errorf("get faild: %w", context.Canceled))
+		_, err = limited.Get(ctx, uri)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(limited.failures).To(Equal(1))
+
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, &net.OpError{Op: "dial"})
+		_, err = limited.Get(ctx, uri)
+		Expect(err).To(HaveOccurred())
+		Expect(limited.circuitOpen()).To(BeTrue())
+
+		By("not closing when the trial call is canceled, and letting the next call be the trial")
+		limited.openedAt = time.Now().Add(-time.Hour * 2)
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, context.Canceled)
+		_, err = limited.Get(ctx, uri)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(limited.state).To(Equal(breakerOpen))
+
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, nil)
+		_, err = limited.Get(ctx, uri)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limited.state).To(Equal(breakerClosed))
+	})
+
+	It("Rate limits calls", func() {
+		limited := newLimitedClient(mockCINP, LimitOptions{QPS: 1, Burst: 1})
+
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, nil)
+		_, err := limited.Get(ctx, uri)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("giving up when the context would expire before the next token")
+		shortCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+		defer cancel()
+		_, err = limited.Get(shortCtx, uri)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Is reported through the factory", func() {
+		ConfigureLimits(LimitOptions{FailureThreshold: 1, OpenDuration: time.Hour})
+		Expect(SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+		Expect(CircuitOpen()).To(BeFalse())
+
+		client, err := GetClient(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		mockCINP.EXPECT().Get(gomock.Any(), uri).Return(nil, errors.New("HTTP Code '504' unhandled"))
+		_, err = client.BuildingStructureGet(ctx, 42)
+		Expect(err).To(HaveOccurred())
+		Expect(CircuitOpen()).To(BeTrue())
+
+		_, err = client.BuildingStructureGet(ctx, 42)
+		Expect(err).To(MatchError(ErrCircuitOpen))
+	})
+
+	It("Only counts errors that mean contractor could not answer", func() {
+		Expect(isUnavailable(nil)).To(BeFalse())
+		Expect(isUnavailable(&cinp.ServerError{})).To(BeFalse())
+		Expect(isUnavailable(&cinp.NotFound{})).To(BeFalse())
+		Expect(isUnavailable(errors.New("HTTP Code '501' unhandled"))).To(BeFalse())
+
+		Expect(isUnavailable(&net.OpError{Op: "dial"})).To(BeTrue())
+		Expect(isUnavailable(context.DeadlineExceeded)).To(BeTrue())
+		for _, code := range []string{"502", "503", "504"} {
+			Expect(isUnavailable(fmt.Errorf("get structure faild: %w", errors.New("HTTP Code '"+code+"' unhandled")))).To(BeTrue())
+		}
+	})
+})
@@ -0,0 +1,199 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	cinp "github.com/cinp/go"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	requestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "contractor_requests_total",
+		Help: "Number of requests made to Contractor by operation, outcome and status class",
+	}, []string{"operation", "outcome", "status_class"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "contractor_request_duration_seconds",
+		Help:    "Duration of requests made to Contractor by operation and outcome",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+
+	tokenRefreshCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "contractor_token_refreshes_total",
+		Help: "Number of times a new Contractor token was obtained",
+	})
+
+	loginFailureCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "contractor_login_failures_total",
+		Help: "Number of failed logins to Contractor",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestsCounter, requestDuration, tokenRefreshCounter, loginFailureCounter)
+}
+
+// ie: "/api/v1/Building/Structure:42:(getJob)" -> "Structure", "getJob"
+var operation_regex = regexp.MustCompile(`^/api/v1/(?:[A-Za-z0-9_]+/)*([A-Za-z0-9_]+)?(?::[^()]*)?(?:\(([A-Za-z0-9_]+)\))?$`)
+
+// operationName makes a low cardinality name for a request, calls are named after the action, ie: "getJob", "doCreate",
+// everything else is the model and verb, ie: "structure_get", "foundation_get", "structure_update"
+func operationName(verb string, uri string) string {
+	match := operation_regex.FindStringSubmatch(uri)
+	if match == nil {
+		return verb
+	}
+
+	if match[2] != "" {
+		return match[2]
+	}
+
+	if match[1] == "" {
+		return verb
+	}
+
+	return strings.ToLower(match[1]) + "_" + verb
+}
+
+// statusClass maps the errors the CInP client returns to the class of HTTP status that caused them
+func statusClass(err error) string {
+	if err == nil {
+		return "2xx"
+	}
+
+	var invalidSession *cinp.InvalidSession
+	var notAuthorized *cinp.NotAuthorized
+	var notFound *cinp.NotFound
+	var invalidRequest *cinp.InvalidRequest
+	var serverError *cinp.ServerError
+	var netError net.Error
+	switch {
+	case errors.As(err, &invalidSession), errors.As(err, &notAuthorized), errors.As(err, &notFound), errors.As(err, &invalidRequest):
+		return "4xx"
+	case errors.As(err, &serverError):
+		return "5xx"
+	case errors.As(err, &netError):
+		return "network"
+	case gatewayError.MatchString(err.Error()):
+		return "5xx"
+	}
+
+	return "network"
+}
+
+// metricsClient wraps a CInP client recording the count and duration of each request.  Like sessionClient,
+// the iterators from ListIds and ListObjects are not recorded.
+type metricsClient struct {
+	cinp.CInPClient
+}
+
+func (m *metricsClient) observe(verb string, uri string, f func() error) error {
+	start := time.Now()
+	err := f()
+
+	operation := operationName(verb, uri)
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+
+	if err == nil {
+		setDegraded(false)
+	} else if isUnavailable(err) {
+		setDegraded(true)
+	}
+
+	requestsCounter.WithLabelValues(operation, outcome, statusClass(err)).Inc()
+	requestDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// Describe implements cinp.CInPClient
+func (m *metricsClient) Describe(ctx context.Context, uri string) (result *cinp.Describe, resultType string, err error) {
+	err = m.observe("describe", uri, func() (err error) {
+		result, resultType, err = m.CInPClient.Describe(ctx, uri)
+		return err
+	})
+	return
+}
+
+// List implements cinp.CInPClient
+func (m *metricsClient) List(ctx context.Context, uri string, filterName string, filterValues map[string]interface{}, position int, count int) (result []string, first int, last int, total int, err error) {
+	err = m.observe("list", uri, func() (err error) {
+		result, first, last, total, err = m.CInPClient.List(ctx, uri, filterName, filterValues, position, count)
+		return err
+	})
+	return
+}
+
+// Get implements cinp.CInPClient
+func (m *metricsClient) Get(ctx context.Context, uri string) (result *cinp.Object, err error) {
+	err = m.observe("get", uri, func() (err error) {
+		result, err = m.CInPClient.Get(ctx, uri)
+		return err
+	})
+	return
+}
+
+// Create implements cinp.CInPClient
+func (m *metricsClient) Create(ctx context.Context, uri string, object cinp.Object) (result *cinp.Object, err error) {
+	err = m.observe("create", uri, func() (err error) {
+		result, err = m.CInPClient.Create(ctx, uri, object)
+		return err
+	})
+	return
+}
+
+// Update implements cinp.CInPClient
+func (m *metricsClient) Update(ctx context.Context, object cinp.Object) (result *cinp.Object, err error) {
+	err = m.observe("update", object.GetURI(), func() (err error) {
+		result, err = m.CInPClient.Update(ctx, object)
+		return err
+	})
+	return
+}
+
+// UpdateMulti implements cinp.CInPClient
+func (m *metricsClient) UpdateMulti(ctx context.Context, uri string, values *map[string]interface{}, result *map[string]cinp.Object) error {
+	return m.observe("update", uri, func() error {
+		return m.CInPClient.UpdateMulti(ctx, uri, values, result)
+	})
+}
+
+// Delete implements cinp.CInPClient
+func (m *metricsClient) Delete(ctx context.Context, object cinp.Object) error {
+	return m.observe("delete", object.GetURI(), func() error {
+		return m.CInPClient.Delete(ctx, object)
+	})
+}
+
+// DeleteURI implements cinp.CInPClient
+func (m *metricsClient) DeleteURI(ctx context.Context, uri string) error {
+	return m.observe("delete", uri, func() error {
+		return m.CInPClient.DeleteURI(ctx, uri)
+	})
+}
+
+// Call implements cinp.CInPClient
+func (m *metricsClient) Call(ctx context.Context, uri string, args *map[string]interface{}, result interface{}) error {
+	return m.observe("call", uri, func() error {
+		return m.CInPClient.Call(ctx, uri, args, result)
+	})
+}
+
+// CallMulti implements cinp.CInPClient
+func (m *metricsClient) CallMulti(ctx context.Context, uri string, args *map[string]interface{}) (result *map[string]map[string]interface{}, err error) {
+	err = m.observe("call", uri, func() (err error) {
+		result, err = m.CInPClient.CallMulti(ctx, uri, args)
+		return err
+	})
+	return
+}
@@ -0,0 +1,68 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+
+	cinp "github.com/cinp/go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+	"t3kton.com/pkg/contractor/test_contractor"
+)
+
+var _ = Describe("Metrics", func() {
+	ctx := context.Background()
+
+	It("Names operations", func() {
+		Expect(operationName("get", "/api/v1/Building/Structure:42:")).To(Equal("structure_get"))
+		Expect(operationName("get", "/api/v1/Building/Foundation:test:")).To(Equal("foundation_get"))
+		Expect(operationName("call", "/api/v1/Building/Structure:42:(getJob)")).To(Equal("getJob"))
+		Expect(operationName("call", "/api/v1/Building/Structure:42:(doCreate)")).To(Equal("doCreate"))
+		Expect(operationName("call", "/api/v1/Building/Structure:1:2:3:(getConfig)")).To(Equal("getConfig"))
+		Expect(operationName("call", "/api/v1/Auth/User(login)")).To(Equal("login"))
+		Expect(operationName("update", "/api/v1/Building/Structure:42:")).To(Equal("structure_update"))
+		Expect(operationName("list", "/api/v1/Foreman/StructureJob")).To(Equal("structurejob_list"))
+		Expect(operationName("describe", "/api/v1/")).To(Equal("describe"))
+	})
+
+	It("Classifies errors", func() {
+		Expect(statusClass(nil)).To(Equal("2xx"))
+		Expect(statusClass(&cinp.NotFound{})).To(Equal("4xx"))
+		Expect(statusClass(&cinp.InvalidSession{})).To(Equal("4xx"))
+		Expect(statusClass(&cinp.ServerError{})).To(Equal("5xx"))
+		Expect(statusClass(errors.New("HTTP Code '503' unhandled"))).To(Equal("5xx"))
+		Expect(statusClass(errors.New("connection refused"))).To(Equal("network"))
+	})
+
+	It("Counts requests", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockCINP := test_contractor.NewMockCInPClient(mockCtrl)
+		client := &metricsClient{CInPClient: mockCINP}
+
+		before := testutil.ToFloat64(requestsCounter.WithLabelValues("doDestroy", "error", "5xx"))
+		mockCINP.EXPECT().Call(gomock.Any(), "/api/v1/Building/Structure:42:(doDestroy)", gomock.Any(), gomock.Any()).Return(&cinp.ServerError{})
+		Expect(client.Call(ctx, "/api/v1/Building/Structure:42:(doDestroy)", nil, nil)).NotTo(Succeed())
+		Expect(testutil.ToFloat64(requestsCounter.WithLabelValues("doDestroy", "error", "5xx"))).To(Equal(before + 1))
+	})
+
+	It("Counts logins", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockCINP := test_contractor.NewMockCInPClient(mockCtrl)
+		Expect(SetupTestingFactory(ctx, mockCINP)).To(Succeed())
+		defer func() { factory = nil }()
+
+		failures := testutil.ToFloat64(loginFailureCounter)
+		mockCINP.EXPECT().Call(gomock.Any(), loginURI, gomock.Any(), gomock.Any()).Return(&cinp.NotAuthorized{})
+		Expect(factory.login(ctx)).NotTo(Succeed())
+		Expect(testutil.ToFloat64(loginFailureCounter)).To(Equal(failures + 1))
+
+		refreshes := testutil.ToFloat64(tokenRefreshCounter)
+		factory.generation.Add(1)
+		mockCINP.EXPECT().Call(gomock.Any(), loginURI, gomock.Any(), gomock.Any()).Return(nil)
+		mockCINP.EXPECT().SetHeader(gomock.Any(), gomock.Any()).Times(2)
+		Expect(factory.login(ctx)).To(Succeed())
+		Expect(testutil.ToFloat64(tokenRefreshCounter)).To(Equal(refreshes + 1))
+	})
+})
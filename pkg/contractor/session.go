@@ -0,0 +1,148 @@
+package contractor
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	cinp "github.com/cinp/go"
+)
+
+const (
+	loginURI  = "/api/v1/Auth/User(login)"
+	logoutURI = "/api/v1/Auth/User(logout)"
+)
+
+// sessionClient wraps a CInP client so that the auth headers are not changed while requests are being made,
+// and so requests rejected with an InvalidSession (ie: the token was revoked) are retried once after logging in again.
+// The iterators from ListIds and ListObjects make their requests after returning, so they are not guarded.
+type sessionClient struct {
+	cinp.CInPClient
+	lock sync.RWMutex
+	// generation returns the current login generation, relogin logs in again if the generation has not moved on
+	generation func() uint64
+	relogin    func(ctx context.Context, generation uint64) error
+}
+
+func newSessionClient(c cinp.CInPClient, generation func() uint64, relogin func(ctx context.Context, generation uint64) error) *sessionClient {
+	return &sessionClient{CInPClient: c, generation: generation, relogin: relogin}
+}
+
+// SetHeader implements cinp.CInPClient
+func (s *sessionClient) SetHeader(name string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.CInPClient.SetHeader(name, value)
+}
+
+// ClearHeader implements cinp.CInPClient
+func (s *sessionClient) ClearHeader(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.CInPClient.ClearHeader(name)
+}
+
+func (s *sessionClient) locked(f func() error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return f()
+}
+
+// do runs f, if the session turns out to be invalid, logs in again and runs f one more time
+func (s *sessionClient) do(ctx context.Context, uri string, f func() error) error {
+	generation := s.generation()
+
+	err := s.locked(f)
+	var invalid *cinp.InvalidSession
+	if !errors.As(err, &invalid) || uri == loginURI || uri == logoutURI {
+		return err
+	}
+
+	if err := s.relogin(ctx, generation); err != nil {
+		return err
+	}
+
+	return s.locked(f)
+}
+
+// Describe implements cinp.CInPClient
+func (s *sessionClient) Describe(ctx context.Context, uri string) (result *cinp.Describe, resultType string, err error) {
+	err = s.do(ctx, uri, func() (err error) {
+		result, resultType, err = s.CInPClient.Describe(ctx, uri)
+		return err
+	})
+	return
+}
+
+// List implements cinp.CInPClient
+func (s *sessionClient) List(ctx context.Context, uri string, filterName string, filterValues map[string]interface{}, position int, count int) (result []string, first int, last int, total int, err error) {
+	err = s.do(ctx, uri, func() (err error) {
+		result, first, last, total, err = s.CInPClient.List(ctx, uri, filterName, filterValues, position, count)
+		return err
+	})
+	return
+}
+
+// Get implements cinp.CInPClient
+func (s *sessionClient) Get(ctx context.Context, uri string) (result *cinp.Object, err error) {
+	err = s.do(ctx, uri, func() (err error) {
+		result, err = s.CInPClient.Get(ctx, uri)
+		return err
+	})
+	return
+}
+
+// Create implements cinp.CInPClient
+func (s *sessionClient) Create(ctx context.Context, uri string, object cinp.Object) (result *cinp.Object, err error) {
+	err = s.do(ctx, uri, func() (err error) {
+		result, err = s.CInPClient.Create(ctx, uri, object)
+		return err
+	})
+	return
+}
+
+// Update implements cinp.CInPClient
+func (s *sessionClient) Update(ctx context.Context, object cinp.Object) (result *cinp.Object, err error) {
+	err = s.do(ctx, "", func() (err error) {
+		result, err = s.CInPClient.Update(ctx, object)
+		return err
+	})
+	return
+}
+
+// UpdateMulti implements cinp.CInPClient
+func (s *sessionClient) UpdateMulti(ctx context.Context, uri string, values *map[string]interface{}, result *map[string]cinp.Object) error {
+	return s.do(ctx, uri, func() error {
+		return s.CInPClient.UpdateMulti(ctx, uri, values, result)
+	})
+}
+
+// Delete implements cinp.CInPClient
+func (s *sessionClient) Delete(ctx context.Context, object cinp.Object) error {
+	return s.do(ctx, "", func() error {
+		return s.CInPClient.Delete(ctx, object)
+	})
+}
+
+// DeleteURI implements cinp.CInPClient
+func (s *sessionClient) DeleteURI(ctx context.Context, uri string) error {
+	return s.do(ctx, uri, func() error {
+		return s.CInPClient.DeleteURI(ctx, uri)
+	})
+}
+
+// Call implements cinp.CInPClient
+func (s *sessionClient) Call(ctx context.Context, uri string, args *map[string]interface{}, result interface{}) error {
+	return s.do(ctx, uri, func() error {
+		return s.CInPClient.Call(ctx, uri, args, result)
+	})
+}
+
+// CallMulti implements cinp.CInPClient
+func (s *sessionClient) CallMulti(ctx context.Context, uri string, args *map[string]interface{}) (result *map[string]map[string]interface{}, err error) {
+	err = s.do(ctx, uri, func() (err error) {
+		result, err = s.CInPClient.CallMulti(ctx, uri, args)
+		return err
+	})
+	return
+}
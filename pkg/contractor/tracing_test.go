@@ -0,0 +1,86 @@
+package contractor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"t3kton.com/pkg/tracing"
+)
+
+var _ = Describe("Tracing", func() {
+	It("Creates a span per request and propagates the trace context", func() {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		previous := otel.GetTracerProvider()
+		tracing.SetProvider(provider)
+		defer otel.SetTracerProvider(previous)
+
+		var traceparent string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceparent = r.Header.Get("traceparent")
+		}))
+		defer server.Close()
+
+		ctx, span := tracing.Start(context.Background(), "Structure.Reconcile")
+		req, err := http.NewRequestWithContext(ctx, "CALL", server.URL+"/api/v1/Building/Structure:42:(getJob)", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := (&http.Client{Transport: newTracingTransport(http.DefaultTransport)}).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		span.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name()).To(Equal("contractor getJob"))
+		Expect(spans[0].Parent().SpanID()).To(Equal(span.SpanContext().SpanID()))
+		Expect(traceparent).To(ContainSubstring(span.SpanContext().TraceID().String()))
+	})
+
+	It("Only traces the requests of the CInP clients", func() {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		previous := otel.GetTracerProvider()
+		tracing.SetProvider(provider)
+		defer otel.SetTracerProvider(previous)
+
+		traceparents := map[string]string{}
+		handler := func(name string) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparents[name] = r.Header.Get("traceparent")
+			})
+		}
+		contractorServer := httptest.NewServer(handler("contractor"))
+		defer contractorServer.Close()
+		otherServer := httptest.NewServer(handler("other"))
+		defer otherServer.Close()
+
+		EnableTracing()
+		defer func() { tracingEnabled = false }()
+		transport, err := newRoundTripper("")
+		Expect(err).NotTo(HaveOccurred())
+		client, err := newHTTPClient(slog.New(slog.NewTextHandler(io.Discard, nil)), contractorServer.URL, transport)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, span := tracing.Start(context.Background(), "Structure.Reconcile")
+		_, err = client.Get(ctx, "/api/v1/Building/Structure:42:")
+		Expect(err).NotTo(HaveOccurred())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, otherServer.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		span.End()
+
+		Expect(recorder.Ended()).To(HaveLen(2))
+		Expect(traceparents["contractor"]).To(ContainSubstring(span.SpanContext().TraceID().String()))
+		Expect(traceparents["other"]).To(BeEmpty())
+	})
+})
@@ -0,0 +1,136 @@
+package contractor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+)
+
+// TLSOptions configures the TLS connection to Contractor
+type TLSOptions struct {
+	// CAFile is a PEM bundle of the CAs to trust for Contractor's certificate, the system pool is used if blank
+	CAFile string
+	// CertFile and KeyFile are the client certificate presented to Contractor, they are re-read when they change
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum TLS version, ie: tls.VersionTLS12, defaults to TLS 1.2
+	MinVersion uint16
+	// ServerName overrides the name Contractor's certificate is verified against
+	ServerName string
+}
+
+// ParseTLSVersion converts "1.2" or "1.3" into the tls version constant
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("unsupported TLS version '%s', expected 1.2 or 1.3", version)
+}
+
+// newTransport creates a copy of the default transport using the TLS options, if there is a client certificate the
+// returned CertWatcher must be started for changes to the certificate to be picked up
+func newTransport(opts TLSOptions) (*http.Transport, *certwatcher.CertWatcher, error) {
+	config := &tls.Config{
+		MinVersion: opts.MinVersion,
+		ServerName: opts.ServerName,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	if opts.CAFile != "" {
+		bundle, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, nil, fmt.Errorf("no certificates found in '%s'", opts.CAFile)
+		}
+	}
+
+	var watcher *certwatcher.CertWatcher
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		var err error
+		watcher, err = certwatcher.New(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.GetClientCertificate = func(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return watcher.GetCertificate(nil)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+
+	return transport, watcher, nil
+}
+
+var (
+	// contractorTransport is the transport set up by ConfigureTLS
+	contractorTransport *http.Transport = nil
+	// tracingEnabled is set by EnableTracing
+	tracingEnabled = false
+)
+
+// ConfigureTLS sets up the TLS options for the connection to Contractor, call before SetupFactory.  The options are
+// only used by the CInP clients SetupFactory makes.  If a client certificate is configured, the returned CertWatcher
+// needs to be added to the manager.
+func ConfigureTLS(opts TLSOptions) (*certwatcher.CertWatcher, error) {
+	transport, watcher, err := newTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	contractorTransport = transport
+
+	return watcher, nil
+}
+
+// EnableTracing makes each CInP request get a client span, named like the request metrics, and the trace context is
+// propagated to Contractor in the request headers, call before SetupFactory.  Only the requests to Contractor are
+// traced, so the trace context is not sent to other hosts and the exporter does not trace itself.
+func EnableTracing() {
+	tracingEnabled = true
+}
+
+// newRoundTripper returns the transport for the CInP clients, the one from ConfigureTLS or a copy of the default
+// transport, going through the proxy if one is set, wrapped for tracing if it is enabled
+func newRoundTripper(proxy string) (http.RoundTripper, error) {
+	var transport *http.Transport
+	if contractorTransport != nil {
+		transport = contractorTransport.Clone()
+	} else {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid contractor proxy '%s': %w", proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if tracingEnabled {
+		return newTracingTransport(transport), nil
+	}
+	return transport, nil
+}
+
+func newTracingTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return "contractor " + operationName(strings.ToLower(r.Method), r.URL.Path)
+	}))
+}
@@ -0,0 +1,149 @@
+package contractor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writeClientCert writes a self signed client certificate and key into dir
+func writeClientCert(dir string, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	Expect(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+	Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)).To(Succeed())
+
+	return certFile, keyFile
+}
+
+var _ = Describe("Transport", func() {
+	var server *httptest.Server
+	var clientName string
+
+	BeforeEach(func() {
+		clientName = ""
+		server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.TLS.PeerCertificates) > 0 {
+				clientName = r.TLS.PeerCertificates[0].Subject.CommonName
+			}
+		}))
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		server.StartTLS()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	writeCA := func(dir string) string {
+		caFile := filepath.Join(dir, "ca.crt")
+		Expect(os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)).To(Succeed())
+		return caFile
+	}
+
+	It("Parses TLS versions", func() {
+		Expect(ParseTLSVersion("1.3")).To(Equal(uint16(tls.VersionTLS13)))
+		Expect(ParseTLSVersion("")).To(Equal(uint16(tls.VersionTLS12)))
+		_, err := ParseTLSVersion("1.0")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Presents the client certificate and trusts the CA bundle", func() {
+		dir := GinkgoT().TempDir()
+		certFile, keyFile := writeClientCert(dir, "operator")
+
+		transport, watcher, err := newTransport(TLSOptions{CAFile: writeCA(dir), CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(watcher).NotTo(BeNil())
+		Expect(transport.TLSClientConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(clientName).To(Equal("operator"))
+	})
+
+	It("Fails without the CA bundle", func() {
+		dir := GinkgoT().TempDir()
+		certFile, keyFile := writeClientCert(dir, "operator")
+
+		transport, _, err := newTransport(TLSOptions{CertFile: certFile, KeyFile: keyFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = (&http.Client{Transport: transport}).Get(server.URL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Fails with an empty CA bundle", func() {
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		Expect(os.WriteFile(caFile, []byte("nothing here"), 0600)).To(Succeed())
+
+		_, _, err := newTransport(TLSOptions{CAFile: caFile})
+		Expect(err).To(MatchError(ContainSubstring("no certificates found")))
+	})
+
+	It("Only uses the TLS options for the CInP clients", func() {
+		dir := GinkgoT().TempDir()
+		certFile, keyFile := writeClientCert(dir, "operator")
+
+		defaultTransport := http.DefaultTransport
+		_, err := ConfigureTLS(TLSOptions{CAFile: writeCA(dir), CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		defer func() { contractorTransport = nil }()
+		Expect(http.DefaultTransport).To(BeIdenticalTo(defaultTransport))
+
+		transport, err := newRoundTripper("")
+		Expect(err).NotTo(HaveOccurred())
+		client, err := newHTTPClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, transport)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Get(context.Background(), "/api/v1/Building/Structure:42:")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clientName).To(Equal("operator"))
+
+		By("not trusting the CA for other clients")
+		_, err = http.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Sends requests through the proxy", func() {
+		transport, err := newRoundTripper("http://proxy:3128")
+		Expect(err).NotTo(HaveOccurred())
+
+		req, err := http.NewRequest(http.MethodGet, "https://contractor/api/v1/", nil)
+		Expect(err).NotTo(HaveOccurred())
+		proxyURL, err := transport.(*http.Transport).Proxy(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxyURL.String()).To(Equal("http://proxy:3128"))
+	})
+})
@@ -0,0 +1,96 @@
+package contractor
+
+import (
+	"reflect"
+
+	cinp "github.com/cinp/go"
+	contractorClient "github.com/t3kton/contractor_goclient"
+)
+
+// registerTypes registers every Contractor model, the same as contractorClient.NewContractorInt does, so the CInP
+// client can decode any of them, ie: the Foundation subtypes.  The client only registers them on the CInP client it
+// makes its self, and the factory has to wrap the CInP client, so the list is copied from contractor_goclient, the
+// tests check it against the client so it is updated when the client is.
+func registerTypes(c cinp.CInPClient) {
+	// Auth
+	c.RegisterType("/api/v1/Auth/User", reflect.TypeOf((*contractorClient.AuthUser)(nil)).Elem())
+	// BluePrint
+	c.RegisterType("/api/v1/BluePrint/BluePrint", reflect.TypeOf((*contractorClient.BlueprintBluePrint)(nil)).Elem())
+	c.RegisterType("/api/v1/BluePrint/FoundationBluePrint", reflect.TypeOf((*contractorClient.BlueprintFoundationBluePrint)(nil)).Elem())
+	c.RegisterType("/api/v1/BluePrint/StructureBluePrint", reflect.TypeOf((*contractorClient.BlueprintStructureBluePrint)(nil)).Elem())
+	c.RegisterType("/api/v1/BluePrint/Script", reflect.TypeOf((*contractorClient.BlueprintScript)(nil)).Elem())
+	c.RegisterType("/api/v1/BluePrint/BluePrintScript", reflect.TypeOf((*contractorClient.BlueprintBluePrintScript)(nil)).Elem())
+	c.RegisterType("/api/v1/BluePrint/PXE", reflect.TypeOf((*contractorClient.BlueprintPXE)(nil)).Elem())
+	// Site
+	c.RegisterType("/api/v1/Site/Site", reflect.TypeOf((*contractorClient.SiteSite)(nil)).Elem())
+	// Survey
+	c.RegisterType("/api/v1/Survey/Plot", reflect.TypeOf((*contractorClient.SurveyPlot)(nil)).Elem())
+	c.RegisterType("/api/v1/Survey/Cartographer", reflect.TypeOf((*contractorClient.SurveyCartographer)(nil)).Elem())
+	// Directory
+	c.RegisterType("/api/v1/Directory/Zone", reflect.TypeOf((*contractorClient.DirectoryZone)(nil)).Elem())
+	c.RegisterType("/api/v1/Directory/Entry", reflect.TypeOf((*contractorClient.DirectoryEntry)(nil)).Elem())
+	// Utilities
+	c.RegisterType("/api/v1/Utilities/Networked", reflect.TypeOf((*contractorClient.UtilitiesNetworked)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/AddressBlock", reflect.TypeOf((*contractorClient.UtilitiesAddressBlock)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/Network", reflect.TypeOf((*contractorClient.UtilitiesNetwork)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/NetworkAddressBlock", reflect.TypeOf((*contractorClient.UtilitiesNetworkAddressBlock)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/NetworkInterface", reflect.TypeOf((*contractorClient.UtilitiesNetworkInterface)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/RealNetworkInterface", reflect.TypeOf((*contractorClient.UtilitiesRealNetworkInterface)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/AbstractNetworkInterface", reflect.TypeOf((*contractorClient.UtilitiesAbstractNetworkInterface)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/AggregatedNetworkInterface", reflect.TypeOf((*contractorClient.UtilitiesAggregatedNetworkInterface)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/BaseAddress", reflect.TypeOf((*contractorClient.UtilitiesBaseAddress)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/Address", reflect.TypeOf((*contractorClient.UtilitiesAddress)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/ReservedAddress", reflect.TypeOf((*contractorClient.UtilitiesReservedAddress)(nil)).Elem())
+	c.RegisterType("/api/v1/Utilities/DynamicAddress", reflect.TypeOf((*contractorClient.UtilitiesDynamicAddress)(nil)).Elem())
+	// Building
+	c.RegisterType("/api/v1/Building/Foundation", reflect.TypeOf((*contractorClient.BuildingFoundation)(nil)).Elem())
+	c.RegisterType("/api/v1/Building/Structure", reflect.TypeOf((*contractorClient.BuildingStructure)(nil)).Elem())
+	c.RegisterType("/api/v1/Building/Complex", reflect.TypeOf((*contractorClient.BuildingComplex)(nil)).Elem())
+	c.RegisterType("/api/v1/Building/ComplexStructure", reflect.TypeOf((*contractorClient.BuildingComplexStructure)(nil)).Elem())
+	c.RegisterType("/api/v1/Building/Dependency", reflect.TypeOf((*contractorClient.BuildingDependency)(nil)).Elem())
+	// Foreman
+	c.RegisterType("/api/v1/Foreman/BaseJob", reflect.TypeOf((*contractorClient.ForemanBaseJob)(nil)).Elem())
+	c.RegisterType("/api/v1/Foreman/FoundationJob", reflect.TypeOf((*contractorClient.ForemanFoundationJob)(nil)).Elem())
+	c.RegisterType("/api/v1/Foreman/StructureJob", reflect.TypeOf((*contractorClient.ForemanStructureJob)(nil)).Elem())
+	c.RegisterType("/api/v1/Foreman/DependencyJob", reflect.TypeOf((*contractorClient.ForemanDependencyJob)(nil)).Elem())
+	c.RegisterType("/api/v1/Foreman/JobLog", reflect.TypeOf((*contractorClient.ForemanJobLog)(nil)).Elem())
+	// SubContractor
+	c.RegisterType("/api/v1/SubContractor/Dispatch", reflect.TypeOf((*contractorClient.SubcontractorDispatch)(nil)).Elem())
+	c.RegisterType("/api/v1/SubContractor/DHCPd", reflect.TypeOf((*contractorClient.SubcontractorDHCPd)(nil)).Elem())
+	// PostOffice
+	c.RegisterType("/api/v1/PostOffice/FoundationPost", reflect.TypeOf((*contractorClient.PostofficeFoundationPost)(nil)).Elem())
+	c.RegisterType("/api/v1/PostOffice/StructurePost", reflect.TypeOf((*contractorClient.PostofficeStructurePost)(nil)).Elem())
+	c.RegisterType("/api/v1/PostOffice/FoundationBox", reflect.TypeOf((*contractorClient.PostofficeFoundationBox)(nil)).Elem())
+	c.RegisterType("/api/v1/PostOffice/StructureBox", reflect.TypeOf((*contractorClient.PostofficeStructureBox)(nil)).Elem())
+	// Records
+	c.RegisterType("/api/v1/Records/Recorder", reflect.TypeOf((*contractorClient.RecordsRecorder)(nil)).Elem())
+	// VirtualBox
+	c.RegisterType("/api/v1/VirtualBox/VirtualBoxComplex", reflect.TypeOf((*contractorClient.VirtualboxVirtualBoxComplex)(nil)).Elem())
+	c.RegisterType("/api/v1/VirtualBox/VirtualBoxFoundation", reflect.TypeOf((*contractorClient.VirtualboxVirtualBoxFoundation)(nil)).Elem())
+	// VCenter
+	c.RegisterType("/api/v1/VCenter/VCenterComplex", reflect.TypeOf((*contractorClient.VcenterVCenterComplex)(nil)).Elem())
+	c.RegisterType("/api/v1/VCenter/VCenterFoundation", reflect.TypeOf((*contractorClient.VcenterVCenterFoundation)(nil)).Elem())
+	// Docker
+	c.RegisterType("/api/v1/Docker/DockerComplex", reflect.TypeOf((*contractorClient.DockerDockerComplex)(nil)).Elem())
+	c.RegisterType("/api/v1/Docker/DockerFoundation", reflect.TypeOf((*contractorClient.DockerDockerFoundation)(nil)).Elem())
+	c.RegisterType("/api/v1/Docker/DockerPort", reflect.TypeOf((*contractorClient.DockerDockerPort)(nil)).Elem())
+	// IPMI
+	c.RegisterType("/api/v1/IPMI/IPMIFoundation", reflect.TypeOf((*contractorClient.IpmiIPMIFoundation)(nil)).Elem())
+	// Manual
+	c.RegisterType("/api/v1/Manual/ManualComplex", reflect.TypeOf((*contractorClient.ManualManualComplex)(nil)).Elem())
+	c.RegisterType("/api/v1/Manual/ManualFoundation", reflect.TypeOf((*contractorClient.ManualManualFoundation)(nil)).Elem())
+	c.RegisterType("/api/v1/Manual/ManualComplexedFoundation", reflect.TypeOf((*contractorClient.ManualManualComplexedFoundation)(nil)).Elem())
+	// Azure
+	c.RegisterType("/api/v1/Azure/AzureComplex", reflect.TypeOf((*contractorClient.AzureAzureComplex)(nil)).Elem())
+	c.RegisterType("/api/v1/Azure/AzureFoundation", reflect.TypeOf((*contractorClient.AzureAzureFoundation)(nil)).Elem())
+	// AMT
+	c.RegisterType("/api/v1/AMT/AMTFoundation", reflect.TypeOf((*contractorClient.AmtAMTFoundation)(nil)).Elem())
+	// Test
+	c.RegisterType("/api/v1/Test/TestComplex", reflect.TypeOf((*contractorClient.TestTestComplex)(nil)).Elem())
+	c.RegisterType("/api/v1/Test/TestFoundation", reflect.TypeOf((*contractorClient.TestTestFoundation)(nil)).Elem())
+	c.RegisterType("/api/v1/Test/TestComplexedFoundation", reflect.TypeOf((*contractorClient.TestTestComplexedFoundation)(nil)).Elem())
+	// AWS
+	c.RegisterType("/api/v1/AWS/AWSEC2Foundation", reflect.TypeOf((*contractorClient.AwsAWSEC2Foundation)(nil)).Elem())
+	// RedFish
+	c.RegisterType("/api/v1/RedFish/RedFishFoundation", reflect.TypeOf((*contractorClient.RedfishRedFishFoundation)(nil)).Elem())
+}
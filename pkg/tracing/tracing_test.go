@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// collector is an in-process OTLP gRPC collector that keeps the spans exported to it
+type collector struct {
+	collectortrace.UnimplementedTraceServiceServer
+	lock  sync.Mutex
+	spans []*tracepb.ResourceSpans
+}
+
+func (c *collector) Export(_ context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.spans = append(c.spans, req.ResourceSpans...)
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+// received returns the service name and span name of each span exported
+func (c *collector) received() map[string]string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	result := map[string]string{}
+	for _, resourceSpans := range c.spans {
+		service := ""
+		for _, attr := range resourceSpans.Resource.Attributes {
+			if attr.Key == "service.name" {
+				service = attr.Value.GetStringValue()
+			}
+		}
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, span := range scopeSpans.Spans {
+				result[span.Name] = service
+			}
+		}
+	}
+	return result
+}
+
+var _ = Describe("Tracing", func() {
+	ctx := context.Background()
+
+	var previous = otel.GetTracerProvider()
+
+	AfterEach(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	It("Does nothing without an endpoint", func() {
+		shutdown, err := Setup(ctx, Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shutdown(ctx)).To(Succeed())
+		Expect(otel.GetTracerProvider()).To(BeIdenticalTo(previous))
+	})
+
+	It("Exports spans to the collector", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		received := &collector{}
+		server := grpc.NewServer()
+		collectortrace.RegisterTraceServiceServer(server, received)
+		go func() {
+			defer GinkgoRecover()
+			Expect(server.Serve(listener)).To(Succeed())
+		}()
+		defer server.Stop()
+
+		shutdown, err := Setup(ctx, Options{Endpoint: listener.Addr().String(), Insecure: true, SampleRatio: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, span := Start(ctx, "Structure.Reconcile")
+		End(span, nil)
+		Expect(shutdown(ctx)).To(Succeed()) // flushes the batcher
+
+		Expect(received.received()).To(Equal(map[string]string{"Structure.Reconcile": serviceName}))
+	})
+
+	It("Does not export unsampled spans", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		received := &collector{}
+		server := grpc.NewServer()
+		collectortrace.RegisterTraceServiceServer(server, received)
+		go func() {
+			defer GinkgoRecover()
+			Expect(server.Serve(listener)).To(Succeed())
+		}()
+		defer server.Stop()
+
+		shutdown, err := Setup(ctx, Options{Endpoint: listener.Addr().String(), Insecure: true, SampleRatio: 0})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, span := Start(ctx, "Structure.Reconcile")
+		End(span, nil)
+		Expect(shutdown(ctx)).To(Succeed())
+
+		Expect(received.received()).To(BeEmpty())
+	})
+
+	It("Records errors on the span", func() {
+		recorder := tracetest.NewSpanRecorder()
+		SetProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+		_, span := Start(ctx, "Structure.ValidateUpdate")
+		End(span, errors.New("invalid state"))
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		Expect(spans[0].Status().Description).To(Equal("invalid state"))
+		Expect(spans[0].Events()).To(HaveLen(1))
+	})
+})